@@ -4,6 +4,7 @@ import (
 	"fmt"
 
 	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 )
 
@@ -28,3 +29,494 @@ func (e ErrOffsetOutOfRange) GRPCStatus() *status.Status {
 func (e ErrOffsetOutOfRange) Error() string {
 	return e.GRPCStatus().Err().Error()
 }
+
+// ErrCorruptRecord is returned when a record read back from the store fails its integrity checks.
+type ErrCorruptRecord struct {
+	Offset uint64
+	Pos    uint64
+}
+
+func (e ErrCorruptRecord) GRPCStatus() *status.Status {
+	st := status.New(codes.DataLoss, fmt.Sprintf("corrupt record at offset: %d", e.Offset))
+	msg := fmt.Sprintf("The record at offset %d is corrupt on disk and can't be served", e.Offset)
+	d := errdetails.LocalizedMessage{
+		Locale:  "en-US",
+		Message: msg,
+	}
+	std, err := st.WithDetails(&d)
+	if err != nil {
+		return st
+	}
+	return std
+}
+
+func (e ErrCorruptRecord) Error() string {
+	return e.GRPCStatus().Err().Error()
+}
+
+// ErrTopicNotFound is returned for records addressed to a topic the log doesn't have.
+type ErrTopicNotFound struct {
+	Topic string
+}
+
+func (e ErrTopicNotFound) GRPCStatus() *status.Status {
+	st := status.New(codes.NotFound, fmt.Sprintf("topic not found: %s", e.Topic))
+	msg := fmt.Sprintf("The log has no topic named %q", e.Topic)
+	d := errdetails.LocalizedMessage{
+		Locale:  "en-US",
+		Message: msg,
+	}
+	std, err := st.WithDetails(&d)
+	if err != nil {
+		return st
+	}
+	return std
+}
+
+func (e ErrTopicNotFound) Error() string {
+	return e.GRPCStatus().Err().Error()
+}
+
+// ErrPartitionNotFound is returned for records addressed to a partition the topic doesn't have.
+type ErrPartitionNotFound struct {
+	Topic     string
+	Partition uint32
+}
+
+func (e ErrPartitionNotFound) GRPCStatus() *status.Status {
+	st := status.New(codes.NotFound, fmt.Sprintf("partition not found: %s/%d", e.Topic, e.Partition))
+	msg := fmt.Sprintf("The topic %q has no partition %d", e.Topic, e.Partition)
+	d := errdetails.LocalizedMessage{
+		Locale:  "en-US",
+		Message: msg,
+	}
+	std, err := st.WithDetails(&d)
+	if err != nil {
+		return st
+	}
+	return std
+}
+
+func (e ErrPartitionNotFound) Error() string {
+	return e.GRPCStatus().Err().Error()
+}
+
+// ErrTopicExists is returned for the creation of a topic the log already has.
+type ErrTopicExists struct {
+	Topic string
+}
+
+func (e ErrTopicExists) GRPCStatus() *status.Status {
+	st := status.New(codes.AlreadyExists, fmt.Sprintf("topic already exists: %s", e.Topic))
+	msg := fmt.Sprintf("The log already has a topic named %q", e.Topic)
+	d := errdetails.LocalizedMessage{
+		Locale:  "en-US",
+		Message: msg,
+	}
+	std, err := st.WithDetails(&d)
+	if err != nil {
+		return st
+	}
+	return std
+}
+
+func (e ErrTopicExists) Error() string {
+	return e.GRPCStatus().Err().Error()
+}
+
+// ErrInvalidTopic is returned for the creation or deletion of a topic the log can't have, or can't do without.
+type ErrInvalidTopic struct {
+	Topic  string
+	Reason string
+}
+
+func (e ErrInvalidTopic) GRPCStatus() *status.Status {
+	st := status.New(codes.InvalidArgument, fmt.Sprintf("invalid topic %q: %s", e.Topic, e.Reason))
+	msg := fmt.Sprintf("The topic %q is invalid: %s", e.Topic, e.Reason)
+	d := errdetails.LocalizedMessage{
+		Locale:  "en-US",
+		Message: msg,
+	}
+	std, err := st.WithDetails(&d)
+	if err != nil {
+		return st
+	}
+	return std
+}
+
+func (e ErrInvalidTopic) Error() string {
+	return e.GRPCStatus().Err().Error()
+}
+
+// ErrInvalidGroup is returned for offsets committed for a consumer group without a valid name.
+type ErrInvalidGroup struct {
+	Group string
+}
+
+func (e ErrInvalidGroup) GRPCStatus() *status.Status {
+	st := status.New(codes.InvalidArgument, fmt.Sprintf("invalid consumer group: %q", e.Group))
+	msg := fmt.Sprintf("%q isn't a valid consumer group name", e.Group)
+	d := errdetails.LocalizedMessage{
+		Locale:  "en-US",
+		Message: msg,
+	}
+	std, err := st.WithDetails(&d)
+	if err != nil {
+		return st
+	}
+	return std
+}
+
+func (e ErrInvalidGroup) Error() string {
+	return e.GRPCStatus().Err().Error()
+}
+
+// ErrNoCommittedOffset is returned for the committed offset of a group that hasn't committed one.
+type ErrNoCommittedOffset struct {
+	Group     string
+	Topic     string
+	Partition uint32
+}
+
+func (e ErrNoCommittedOffset) GRPCStatus() *status.Status {
+	st := status.New(codes.NotFound, fmt.Sprintf("no committed offset: %s %s/%d", e.Group, e.Topic, e.Partition))
+	msg := fmt.Sprintf("The group %q hasn't committed an offset for partition %d of %q", e.Group, e.Partition, e.Topic)
+	d := errdetails.LocalizedMessage{
+		Locale:  "en-US",
+		Message: msg,
+	}
+	std, err := st.WithDetails(&d)
+	if err != nil {
+		return st
+	}
+	return std
+}
+
+func (e ErrNoCommittedOffset) Error() string {
+	return e.GRPCStatus().Err().Error()
+}
+
+// ErrUnknownMember is returned for requests from a consumer that isn't a member of the group, or no longer is.
+type ErrUnknownMember struct {
+	Group    string
+	MemberID string
+}
+
+func (e ErrUnknownMember) GRPCStatus() *status.Status {
+	st := status.New(codes.NotFound, fmt.Sprintf("unknown member: %s %s", e.Group, e.MemberID))
+	msg := fmt.Sprintf("%q isn't a member of the group %q, it has to join it again", e.MemberID, e.Group)
+	d := errdetails.LocalizedMessage{
+		Locale:  "en-US",
+		Message: msg,
+	}
+	std, err := st.WithDetails(&d)
+	if err != nil {
+		return st
+	}
+	return std
+}
+
+func (e ErrUnknownMember) Error() string {
+	return e.GRPCStatus().Err().Error()
+}
+
+// ErrStaleGeneration is returned for requests from a member made in a generation the group has been
+// rebalanced out of.
+type ErrStaleGeneration struct {
+	Group      string
+	Generation uint64
+}
+
+func (e ErrStaleGeneration) GRPCStatus() *status.Status {
+	st := status.New(codes.FailedPrecondition, fmt.Sprintf("stale generation: %s %d", e.Group, e.Generation))
+	msg := fmt.Sprintf("The group %q was rebalanced past generation %d, its members have to join it again", e.Group, e.Generation)
+	d := errdetails.LocalizedMessage{
+		Locale:  "en-US",
+		Message: msg,
+	}
+	std, err := st.WithDetails(&d)
+	if err != nil {
+		return st
+	}
+	return std
+}
+
+func (e ErrStaleGeneration) Error() string {
+	return e.GRPCStatus().Err().Error()
+}
+
+// ErrPartitionNotAssigned is returned for offsets committed by a member for a partition it wasn't handed.
+type ErrPartitionNotAssigned struct {
+	Group     string
+	MemberID  string
+	Topic     string
+	Partition uint32
+}
+
+func (e ErrPartitionNotAssigned) GRPCStatus() *status.Status {
+	st := status.New(codes.FailedPrecondition, fmt.Sprintf("partition not assigned: %s %s %s/%d", e.Group, e.MemberID, e.Topic, e.Partition))
+	msg := fmt.Sprintf("%q of the group %q wasn't handed the partition %d of the topic %q", e.MemberID, e.Group, e.Partition, e.Topic)
+	d := errdetails.LocalizedMessage{
+		Locale:  "en-US",
+		Message: msg,
+	}
+	std, err := st.WithDetails(&d)
+	if err != nil {
+		return st
+	}
+	return std
+}
+
+func (e ErrPartitionNotAssigned) Error() string {
+	return e.GRPCStatus().Err().Error()
+}
+
+// ErrUnknownStrategy is returned for members joining a group with an assignment strategy the coordinator doesn't have.
+type ErrUnknownStrategy struct {
+	Strategy string
+}
+
+func (e ErrUnknownStrategy) GRPCStatus() *status.Status {
+	st := status.New(codes.InvalidArgument, fmt.Sprintf("unknown assignment strategy: %s", e.Strategy))
+	msg := fmt.Sprintf("There's no assignment strategy named %q", e.Strategy)
+	d := errdetails.LocalizedMessage{
+		Locale:  "en-US",
+		Message: msg,
+	}
+	std, err := st.WithDetails(&d)
+	if err != nil {
+		return st
+	}
+	return std
+}
+
+func (e ErrUnknownStrategy) Error() string {
+	return e.GRPCStatus().Err().Error()
+}
+
+// ErrInconsistentStrategy is returned for members joining a group with another assignment strategy than its members use.
+type ErrInconsistentStrategy struct {
+	Group         string
+	Strategy      string
+	GroupStrategy string
+}
+
+func (e ErrInconsistentStrategy) GRPCStatus() *status.Status {
+	st := status.New(codes.FailedPrecondition, fmt.Sprintf("inconsistent assignment strategy: %s uses %s, not %s", e.Group, e.GroupStrategy, e.Strategy))
+	msg := fmt.Sprintf("The members of the group %q use the %q assignment strategy, not %q", e.Group, e.GroupStrategy, e.Strategy)
+	d := errdetails.LocalizedMessage{
+		Locale:  "en-US",
+		Message: msg,
+	}
+	std, err := st.WithDetails(&d)
+	if err != nil {
+		return st
+	}
+	return std
+}
+
+func (e ErrInconsistentStrategy) Error() string {
+	return e.GRPCStatus().Err().Error()
+}
+
+// ErrOutOfOrderSequence is returned for records from an idempotent producer that don't carry the sequence
+// number the partition expects from it next, and aren't a retry of one of its latest records either.
+type ErrOutOfOrderSequence struct {
+	ProducerID uint64
+	Sequence   uint32
+	Expected   uint32
+}
+
+func (e ErrOutOfOrderSequence) GRPCStatus() *status.Status {
+	st := status.New(codes.FailedPrecondition, fmt.Sprintf("out of order sequence: producer %d sent %d, expected %d", e.ProducerID, e.Sequence, e.Expected))
+	msg := fmt.Sprintf("The partition expects sequence number %d from producer %d next, not %d", e.Expected, e.ProducerID, e.Sequence)
+	d := errdetails.LocalizedMessage{
+		Locale:  "en-US",
+		Message: msg,
+	}
+	std, err := st.WithDetails(&d)
+	if err != nil {
+		return st
+	}
+	return std
+}
+
+func (e ErrOutOfOrderSequence) Error() string {
+	return e.GRPCStatus().Err().Error()
+}
+
+// ErrInvalidProducer is returned for transactions from a producer without a producer ID, see InitProducer.
+type ErrInvalidProducer struct {
+	ProducerID uint64
+}
+
+func (e ErrInvalidProducer) GRPCStatus() *status.Status {
+	st := status.New(codes.InvalidArgument, fmt.Sprintf("invalid producer: %d", e.ProducerID))
+	msg := fmt.Sprintf("Transactions need a producer ID handed out by InitProducer, not %d", e.ProducerID)
+	d := errdetails.LocalizedMessage{
+		Locale:  "en-US",
+		Message: msg,
+	}
+	std, err := st.WithDetails(&d)
+	if err != nil {
+		return st
+	}
+	return std
+}
+
+func (e ErrInvalidProducer) Error() string {
+	return e.GRPCStatus().Err().Error()
+}
+
+// ErrTxnInProgress is returned for a transaction begun by a producer that already has one in progress.
+type ErrTxnInProgress struct {
+	ProducerID uint64
+}
+
+func (e ErrTxnInProgress) GRPCStatus() *status.Status {
+	st := status.New(codes.FailedPrecondition, fmt.Sprintf("transaction in progress: producer %d", e.ProducerID))
+	msg := fmt.Sprintf("Producer %d already has a transaction in progress, it has to commit or abort it first", e.ProducerID)
+	d := errdetails.LocalizedMessage{
+		Locale:  "en-US",
+		Message: msg,
+	}
+	std, err := st.WithDetails(&d)
+	if err != nil {
+		return st
+	}
+	return std
+}
+
+func (e ErrTxnInProgress) Error() string {
+	return e.GRPCStatus().Err().Error()
+}
+
+// ErrNoTxn is returned for transactional requests from a producer without a transaction in progress,
+// because it never began one or it was already committed, aborted or timed out.
+type ErrNoTxn struct {
+	ProducerID uint64
+}
+
+func (e ErrNoTxn) GRPCStatus() *status.Status {
+	st := status.New(codes.FailedPrecondition, fmt.Sprintf("no transaction in progress: producer %d", e.ProducerID))
+	msg := fmt.Sprintf("Producer %d has no transaction in progress, it has to begin one first", e.ProducerID)
+	d := errdetails.LocalizedMessage{
+		Locale:  "en-US",
+		Message: msg,
+	}
+	std, err := st.WithDetails(&d)
+	if err != nil {
+		return st
+	}
+	return std
+}
+
+func (e ErrNoTxn) Error() string {
+	return e.GRPCStatus().Err().Error()
+}
+
+// ErrConflict is returned for appends that expected the log, or the latest record with their key, at
+// another offset than it is: another writer appended first.
+type ErrConflict struct {
+	// Key is nil for the appends that expected the log's next offset
+	Key      []byte
+	Expected int64
+	Actual   int64
+}
+
+func (e ErrConflict) GRPCStatus() *status.Status {
+	var st *status.Status
+	var msg string
+	if e.Key == nil {
+		st = status.New(codes.FailedPrecondition, fmt.Sprintf("conflict: expected next offset %d, is %d", e.Expected, e.Actual))
+		msg = fmt.Sprintf("The record was expected at offset %d but would go to %d, another writer appended first", e.Expected, e.Actual)
+	} else {
+		st = status.New(codes.FailedPrecondition, fmt.Sprintf("conflict: expected key %q at offset %d, is %d", e.Key, e.Expected, e.Actual))
+		msg = fmt.Sprintf("The latest record with the key %q was expected at offset %d but is at %d, another writer appended first", e.Key, e.Expected, e.Actual)
+	}
+	d := errdetails.LocalizedMessage{
+		Locale:  "en-US",
+		Message: msg,
+	}
+	std, err := st.WithDetails(&d)
+	if err != nil {
+		return st
+	}
+	return std
+}
+
+func (e ErrConflict) Error() string {
+	return e.GRPCStatus().Err().Error()
+}
+
+// ErrExpectedKey is returned for appends that expect the latest offset of the record's key, when the
+// record has no key.
+type ErrExpectedKey struct{}
+
+func (e ErrExpectedKey) GRPCStatus() *status.Status {
+	st := status.New(codes.InvalidArgument, "expected key offset for a record without a key")
+	msg := "Expecting the latest offset of a key needs a record with a key"
+	d := errdetails.LocalizedMessage{
+		Locale:  "en-US",
+		Message: msg,
+	}
+	std, err := st.WithDetails(&d)
+	if err != nil {
+		return st
+	}
+	return std
+}
+
+func (e ErrExpectedKey) Error() string {
+	return e.GRPCStatus().Err().Error()
+}
+
+// ErrUnknownCodec is returned for batches compressed with a codec the log doesn't have registered, see
+// log.RegisterCodec. The batch isn't corrupt, and reads again once the codec's registered.
+type ErrUnknownCodec struct {
+	ID byte
+}
+
+func (e ErrUnknownCodec) GRPCStatus() *status.Status {
+	st := status.New(codes.FailedPrecondition, fmt.Sprintf("unknown codec id: %d", e.ID))
+	msg := fmt.Sprintf("The records were compressed with codec %d, which isn't registered", e.ID)
+	d := errdetails.LocalizedMessage{
+		Locale:  "en-US",
+		Message: msg,
+	}
+	std, err := st.WithDetails(&d)
+	if err != nil {
+		return st
+	}
+	return std
+}
+
+func (e ErrUnknownCodec) Error() string {
+	return e.GRPCStatus().Err().Error()
+}
+
+// ErrInvalidFilter is returned for consume requests whose filter doesn't parse.
+type ErrInvalidFilter struct {
+	Filter string
+	// Pos is the byte offset in the filter the error was found at
+	Pos    int
+	Reason string
+}
+
+func (e ErrInvalidFilter) GRPCStatus() *status.Status {
+	st := status.New(codes.InvalidArgument, fmt.Sprintf("invalid filter at %d: %s", e.Pos, e.Reason))
+	msg := fmt.Sprintf("The filter %q is invalid at byte %d: %s", e.Filter, e.Pos, e.Reason)
+	d := errdetails.LocalizedMessage{
+		Locale:  "en-US",
+		Message: msg,
+	}
+	std, err := st.WithDetails(&d)
+	if err != nil {
+		return st
+	}
+	return std
+}
+
+func (e ErrInvalidFilter) Error() string {
+	return e.GRPCStatus().Err().Error()
+}
@@ -9,6 +9,7 @@ import (
 
 	"github.com/sant470/distlogs/internal/auth"
 	"github.com/sant470/distlogs/internal/discovery"
+	"github.com/sant470/distlogs/internal/group"
 	"github.com/sant470/distlogs/internal/log"
 	"github.com/sant470/distlogs/internal/server"
 	"go.uber.org/zap"
@@ -18,7 +19,7 @@ import (
 
 type Agent struct {
 	Config
-	log          *log.Log
+	log          *log.LogManager
 	server       *grpc.Server
 	membership   *discovery.Membership
 	replicator   *log.Replicator
@@ -76,7 +77,7 @@ func (a *Agent) setupLogger() error {
 }
 func (a *Agent) setupLog() error {
 	var err error
-	a.log, err = log.NewLog(
+	a.log, err = log.NewLogManager(
 		a.Config.DataDir,
 		log.Config{},
 	)
@@ -86,8 +87,9 @@ func (a *Agent) setupLog() error {
 func (a *Agent) setupServer() error {
 	authorizer := auth.NewAuthorizer(a.Config.ACLModelFile, a.Config.ACLPolicyFile)
 	serverConfig := &server.Config{
-		CommitLog:  a.log,
-		Authorizer: authorizer,
+		CommitLog:   a.log,
+		Coordinator: group.New(group.Config{Partitions: a.log.Partitions}),
+		Authorizer:  authorizer,
 	}
 	var opts []grpc.ServerOption
 	if a.Config.ServerTLSConfig != nil {
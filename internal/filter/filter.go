@@ -0,0 +1,257 @@
+/*
+	Filters let consumers have the server skip the records they'd throw away. A filter compares a record's
+	key, headers and timestamps to values, and combines the comparisons with &&, || and !, see ConsumeRequest
+	in api/v1/log.proto for the syntax. It's parsed once per request into a tree of closures, so matching a
+	record allocates nothing.
+*/
+
+package filter
+
+import (
+	"bytes"
+	"cmp"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/sant470/distlogs/api/v1"
+)
+
+// Filter matches records. A nil Filter matches every record.
+type Filter struct {
+	match func(*api.Record) bool
+}
+
+// Parse parses the filter expression. It returns a nil Filter for an empty one, and api.ErrInvalidFilter
+// for one that doesn't parse.
+func Parse(expr string) (*Filter, error) {
+	if strings.TrimSpace(expr) == "" {
+		return nil, nil
+	}
+	tokens, err := lex(expr)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{expr: expr, tokens: tokens}
+	match, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokEOF {
+		return nil, invalid(expr, t.pos, fmt.Sprintf("unexpected %q", t.text))
+	}
+	return &Filter{match: match}, nil
+}
+
+// Match reports whether the filter matches the record.
+func (f *Filter) Match(record *api.Record) bool {
+	return f == nil || f.match(record)
+}
+
+func invalid(expr string, pos int, reason string) error {
+	return api.ErrInvalidFilter{Filter: expr, Pos: pos, Reason: reason}
+}
+
+type parser struct {
+	expr   string
+	tokens []token
+	i      int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.i]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.i]
+	if t.kind != tokEOF {
+		p.i++
+	}
+	return t
+}
+
+// accept consumes the next token if it's the operator.
+func (p *parser) accept(op string) bool {
+	if t := p.peek(); t.kind == tokOp && t.text == op {
+		p.i++
+		return true
+	}
+	return false
+}
+
+func (p *parser) or() (func(*api.Record) bool, error) {
+	left, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("||") {
+		right, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+		l := left
+		left = func(r *api.Record) bool { return l(r) || right(r) }
+	}
+	return left, nil
+}
+
+func (p *parser) and() (func(*api.Record) bool, error) {
+	left, err := p.not()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("&&") {
+		right, err := p.not()
+		if err != nil {
+			return nil, err
+		}
+		l := left
+		left = func(r *api.Record) bool { return l(r) && right(r) }
+	}
+	return left, nil
+}
+
+func (p *parser) not() (func(*api.Record) bool, error) {
+	if p.accept("!") {
+		operand, err := p.not()
+		if err != nil {
+			return nil, err
+		}
+		return func(r *api.Record) bool { return !operand(r) }, nil
+	}
+	if t := p.peek(); p.accept("(") {
+		inner, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		if !p.accept(")") {
+			return nil, invalid(p.expr, p.peek().pos, fmt.Sprintf("missing ) for the ( at %d", t.pos))
+		}
+		return inner, nil
+	}
+	return p.comparison()
+}
+
+// field is a field of the record, with the way to read it.
+type field struct {
+	name string
+	// bytes reads keys and headers, reporting whether the record has the field
+	bytes func(*api.Record) ([]byte, bool)
+	// int reads timestamps
+	int func(*api.Record) int64
+}
+
+func (p *parser) comparison() (func(*api.Record) bool, error) {
+	f, err := p.field()
+	if err != nil {
+		return nil, err
+	}
+	t := p.peek()
+	if !isComparison(t) {
+		// a key or header alone tests whether the record has it
+		if f.bytes == nil {
+			return nil, invalid(p.expr, t.pos, fmt.Sprintf("expected a comparison after %s", f.name))
+		}
+		return func(r *api.Record) bool {
+			_, ok := f.bytes(r)
+			return ok
+		}, nil
+	}
+	p.next()
+	op := t.text
+	v := p.next()
+	if f.bytes != nil {
+		if v.kind != tokString {
+			return nil, invalid(p.expr, v.pos, fmt.Sprintf("%s compares to a string", f.name))
+		}
+		want := []byte(v.text)
+		return func(r *api.Record) bool {
+			got, ok := f.bytes(r)
+			return ok && holds(op, bytes.Compare(got, want))
+		}, nil
+	}
+	var want int64
+	switch v.kind {
+	case tokNumber:
+		want = v.num
+	case tokString:
+		ts, err := time.Parse(time.RFC3339Nano, v.text)
+		if err != nil {
+			return nil, invalid(p.expr, v.pos, fmt.Sprintf("%q isn't an RFC 3339 timestamp", v.text))
+		}
+		want = ts.UnixNano()
+	default:
+		return nil, invalid(p.expr, v.pos, fmt.Sprintf("%s compares to a number or a timestamp", f.name))
+	}
+	return func(r *api.Record) bool { return holds(op, cmp.Compare(f.int(r), want)) }, nil
+}
+
+func (p *parser) field() (field, error) {
+	t := p.next()
+	if t.kind != tokIdent {
+		return field{}, invalid(p.expr, t.pos, "expected key, header, ts or producer_ts")
+	}
+	switch {
+	case t.text == "key":
+		return field{name: t.text, bytes: func(r *api.Record) ([]byte, bool) {
+			return r.Key, len(r.Key) > 0
+		}}, nil
+	case t.text == "ts":
+		return field{name: t.text, int: func(r *api.Record) int64 { return r.Timestamp }}, nil
+	case t.text == "producer_ts":
+		return field{name: t.text, int: func(r *api.Record) int64 { return r.ProducerTimestamp }}, nil
+	case strings.HasPrefix(t.text, "header.") && len(t.text) > len("header."):
+		return headerField(strings.TrimPrefix(t.text, "header.")), nil
+	case t.text == "header" && p.accept("["):
+		name := p.next()
+		if name.kind != tokString {
+			return field{}, invalid(p.expr, name.pos, "expected a header name")
+		}
+		if !p.accept("]") {
+			return field{}, invalid(p.expr, p.peek().pos, "expected ]")
+		}
+		return headerField(name.text), nil
+	}
+	return field{}, invalid(p.expr, t.pos, fmt.Sprintf("unknown field %q", t.text))
+}
+
+// headerField reads the record's last header with the name, later headers overriding earlier ones.
+func headerField(name string) field {
+	key := []byte(name)
+	return field{name: "header " + name, bytes: func(r *api.Record) ([]byte, bool) {
+		for i := len(r.Headers) - 1; i >= 0; i-- {
+			if bytes.Equal(r.Headers[i].Key, key) {
+				return r.Headers[i].Value, true
+			}
+		}
+		return nil, false
+	}}
+}
+
+func isComparison(t token) bool {
+	if t.kind != tokOp {
+		return false
+	}
+	switch t.text {
+	case "==", "!=", "<", "<=", ">", ">=":
+		return true
+	}
+	return false
+}
+
+// holds reports whether the comparison holds, given how its sides compare.
+func holds(op string, c int) bool {
+	switch op {
+	case "==":
+		return c == 0
+	case "!=":
+		return c != 0
+	case "<":
+		return c < 0
+	case "<=":
+		return c <= 0
+	case ">":
+		return c > 0
+	}
+	return c >= 0
+}
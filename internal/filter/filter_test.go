@@ -0,0 +1,78 @@
+package filter
+
+import (
+	"testing"
+	"time"
+
+	"github.com/sant470/distlogs/api/v1"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFilterMatch(t *testing.T) {
+	ts := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC).UnixNano()
+	order := &api.Record{
+		Key: []byte("user-1"),
+		Headers: []*api.Header{
+			{Key: []byte("type"), Value: []byte("order")},
+			{Key: []byte("trace-id"), Value: []byte("abc")},
+			{Key: []byte("content type"), Value: []byte("json")},
+		},
+		Timestamp:         ts,
+		ProducerTimestamp: 100,
+	}
+	refund := &api.Record{
+		Headers:   []*api.Header{{Key: []byte("type"), Value: []byte("order")}, {Key: []byte("type"), Value: []byte("refund")}},
+		Timestamp: ts - 1,
+	}
+	plain := &api.Record{Value: []byte("hello"), Timestamp: ts + 1}
+
+	for expr, want := range map[string][]bool{
+		"":                                 {true, true, true},
+		`header.type == "order"`:           {true, false, false},
+		`header.type != "order"`:           {false, true, false},
+		`!(header.type == "order")`:        {false, true, true},
+		`header.trace-id`:                  {true, false, false},
+		`header["content type"] == "json"`: {true, false, false},
+		`key`:                              {true, false, false},
+		`key == "user-1"`:                  {true, false, false},
+		`key < "user-2" && key >= "user"`:  {true, false, false},
+		`ts >= "2024-01-01T00:00:00Z"`:     {true, false, true},
+		`ts < ` + "1704067200000000000":    {false, true, false},
+		`producer_ts > 50`:                 {true, false, false},
+		`header.type == "refund" || ts > "2024-01-01T00:00:00Z"`: {false, true, true},
+		`!key && (ts <= "2024-01-01T00:00:00Z" || header.x)`:     {false, true, false},
+	} {
+		t.Run(expr, func(t *testing.T) {
+			f, err := Parse(expr)
+			require.NoError(t, err)
+			for i, record := range []*api.Record{order, refund, plain} {
+				require.Equal(t, want[i], f.Match(record), "record %d", i)
+			}
+		})
+	}
+}
+
+func TestFilterParseError(t *testing.T) {
+	for expr, pos := range map[string]int{
+		`header.type == order`:      15,
+		`header.type == 1`:          15,
+		`ts > "yesterday"`:          5,
+		`ts`:                        2,
+		`value == "x"`:              0,
+		`(key == "x"`:               11,
+		`key == "x`:                 7,
+		`key == "x" key`:            11,
+		`key = "x"`:                 4,
+		`header[type] == "x"`:       7,
+		`ts > 99999999999999999999`: 5,
+	} {
+		t.Run(expr, func(t *testing.T) {
+			_, err := Parse(expr)
+			require.Error(t, err)
+			invalid, ok := err.(api.ErrInvalidFilter)
+			require.True(t, ok)
+			require.Equal(t, expr, invalid.Filter)
+			require.Equal(t, pos, invalid.Pos, invalid.Reason)
+		})
+	}
+}
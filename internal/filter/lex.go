@@ -0,0 +1,102 @@
+package filter
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokIdent
+	tokString
+	tokNumber
+	tokOp
+)
+
+type token struct {
+	kind tokenKind
+	// text is the operator or identifier as written, or the string's value once unquoted
+	text string
+	num  int64
+	pos  int
+}
+
+// operators are matched longest first.
+var operators = []string{"==", "!=", "<=", ">=", "&&", "||", "<", ">", "!", "(", ")", "[", "]"}
+
+// lex splits the filter into tokens, ending with tokEOF.
+func lex(expr string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(expr); {
+		c := expr[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case isIdentStart(c):
+			j := i + 1
+			for j < len(expr) && isIdent(expr[j]) {
+				j++
+			}
+			tokens = append(tokens, token{kind: tokIdent, text: expr[i:j], pos: i})
+			i = j
+		case c == '"':
+			j := i + 1
+			for j < len(expr) && expr[j] != '"' {
+				if expr[j] == '\\' {
+					j++
+				}
+				j++
+			}
+			if j >= len(expr) {
+				return nil, invalid(expr, i, "unterminated string")
+			}
+			s, err := strconv.Unquote(expr[i : j+1])
+			if err != nil {
+				return nil, invalid(expr, i, "invalid string")
+			}
+			tokens = append(tokens, token{kind: tokString, text: s, pos: i})
+			i = j + 1
+		case isDigit(c) || c == '-' && i+1 < len(expr) && isDigit(expr[i+1]):
+			j := i + 1
+			for j < len(expr) && isDigit(expr[j]) {
+				j++
+			}
+			n, err := strconv.ParseInt(expr[i:j], 10, 64)
+			if err != nil {
+				return nil, invalid(expr, i, "number out of range")
+			}
+			tokens = append(tokens, token{kind: tokNumber, text: expr[i:j], num: n, pos: i})
+			i = j
+		default:
+			op := ""
+			for _, o := range operators {
+				if strings.HasPrefix(expr[i:], o) {
+					op = o
+					break
+				}
+			}
+			if op == "" {
+				return nil, invalid(expr, i, fmt.Sprintf("unexpected character %q", c))
+			}
+			tokens = append(tokens, token{kind: tokOp, text: op, pos: i})
+			i += len(op)
+		}
+	}
+	return append(tokens, token{kind: tokEOF, pos: len(expr)}), nil
+}
+
+func isIdentStart(c byte) bool {
+	return c == '_' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z'
+}
+
+// isIdent allows dots and dashes after the first character, for header names like header.trace-id.
+func isIdent(c byte) bool {
+	return isIdentStart(c) || isDigit(c) || c == '.' || c == '-'
+}
+
+func isDigit(c byte) bool {
+	return '0' <= c && c <= '9'
+}
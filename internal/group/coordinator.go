@@ -0,0 +1,275 @@
+/*
+	The coordinator keeps track of the consumer groups' members and shares each group's partitions out among
+	them. Members join with the topics they consume and heartbeat to stay in the group; a member that
+	hasn't been heard from within the session timeout is dropped the next time anyone touches its group.
+	Every change to a group's members rebalances it: its generation goes up and its partitions are assigned
+	again. Heartbeats and offset commits made in an earlier generation are rejected, which is how members
+	find out they have to join again, and how a member that was rebalanced out is kept from committing offsets
+	for partitions it no longer has. A member only commits offsets for the partitions it was handed.
+	A partition taken from one member and assigned to another isn't handed over until the first one gives it
+	up by joining again, so two members never consume it at once. The member it's handed to is kept waiting
+	without it until then, and the group moves to its next generation once it's given up, for the members to
+	join again and get what they're assigned.
+	A group is deleted once it has no members left. Joining a group drops the timed out members of the others.
+	Membership lives in memory only: after a restart members find out they're unknown and join again.
+*/
+
+package group
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/sant470/distlogs/api/v1"
+)
+
+type Config struct {
+	// SessionTimeout drops the members that haven't joined or heartbeat for that long, 10s if left zero.
+	SessionTimeout time.Duration
+	// Partitions returns how many partitions a topic has.
+	Partitions func(topic string) (int, error)
+}
+
+// Assignment is what a member gets for joining its group.
+type Assignment struct {
+	MemberID   string
+	Generation uint64
+	Partitions []Partition
+}
+
+type Coordinator struct {
+	Config
+	mu     sync.Mutex
+	groups map[string]*group
+	nextID uint64
+}
+
+type group struct {
+	generation uint64
+	strategy   string
+	members    map[string]*member
+}
+
+type member struct {
+	Member
+	heartbeat time.Time
+	// assigned is what the latest rebalance assigned the member, partitions what it's been handed of it
+	assigned   []Partition
+	partitions []Partition
+}
+
+func New(c Config) *Coordinator {
+	if c.SessionTimeout == 0 {
+		c.SessionTimeout = 10 * time.Second
+	}
+	return &Coordinator{
+		Config: c,
+		groups: make(map[string]*group),
+	}
+}
+
+// Join adds a member to the group, or updates the topics of one that joined before, and returns its
+// partitions. A member joins with an empty ID the first time and gets one assigned. Strategy names how
+// the group's partitions are assigned, "range" if left empty.
+func (c *Coordinator) Join(groupID, memberID string, topics []string, strategy string) (Assignment, error) {
+	if strategy == "" {
+		strategy = "range"
+	}
+	if _, ok := strategies[strategy]; !ok {
+		return Assignment{}, api.ErrUnknownStrategy{Strategy: strategy}
+	}
+	for _, topic := range topics {
+		if _, err := c.Partitions(topic); err != nil {
+			return Assignment{}, err
+		}
+	}
+	topics = slices.Sorted(slices.Values(topics))
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	// the group's deleted again if the member doesn't make it in
+	defer c.prune(groupID)
+	now := time.Now()
+	for id, g := range c.groups {
+		c.expire(g, now)
+		c.prune(id)
+	}
+	g, ok := c.groups[groupID]
+	if !ok {
+		g = &group{members: make(map[string]*member)}
+		c.groups[groupID] = g
+	}
+	if len(g.members) > 0 && g.strategy != strategy {
+		return Assignment{}, api.ErrInconsistentStrategy{Group: groupID, Strategy: strategy, GroupStrategy: g.strategy}
+	}
+	g.strategy = strategy
+	m, ok := g.members[memberID]
+	switch {
+	case memberID != "" && !ok:
+		return Assignment{}, api.ErrUnknownMember{Group: groupID, MemberID: memberID}
+	case !ok:
+		c.nextID++
+		m = &member{Member: Member{ID: fmt.Sprintf("%s-%d", groupID, c.nextID), Topics: topics}}
+		g.members[m.ID] = m
+		c.rebalance(g)
+	case !slices.Equal(m.Topics, topics):
+		m.Topics = topics
+		c.rebalance(g)
+	}
+	m.heartbeat = now
+	c.handOver(g, m)
+	return Assignment{MemberID: m.ID, Generation: g.generation, Partitions: m.partitions}, nil
+}
+
+// handOver hands the member the partitions it's assigned, but for the ones another member still has, and
+// takes back the ones it isn't assigned anymore. If a member's waiting on those, the group moves to its
+// next generation. It's called with c.mu held.
+func (c *Coordinator) handOver(g *group, m *member) {
+	released := slices.ContainsFunc(m.partitions, func(p Partition) bool {
+		return !slices.Contains(m.assigned, p)
+	})
+	m.partitions = slices.DeleteFunc(slices.Clone(m.assigned), func(p Partition) bool {
+		owner := c.owner(g, p)
+		return owner != nil && owner != m
+	})
+	if !released {
+		return
+	}
+	for _, other := range g.members {
+		if len(other.partitions) < len(other.assigned) {
+			g.generation++
+			return
+		}
+	}
+}
+
+// owner returns the member that has the partition, if any. It's called with c.mu held.
+func (c *Coordinator) owner(g *group, p Partition) *member {
+	for _, m := range g.members {
+		if slices.Contains(m.partitions, p) {
+			return m
+		}
+	}
+	return nil
+}
+
+// Heartbeat keeps the member in the group. It fails with api.ErrStaleGeneration once the group has been
+// rebalanced past the member's generation.
+func (c *Coordinator) Heartbeat(groupID, memberID string, generation uint64) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	defer c.prune(groupID)
+	g, m, err := c.member(groupID, memberID)
+	if err != nil {
+		return err
+	}
+	m.heartbeat = time.Now()
+	if generation != g.generation {
+		return api.ErrStaleGeneration{Group: groupID, Generation: generation}
+	}
+	return nil
+}
+
+// Leave removes the member from the group, handing its partitions to the others.
+func (c *Coordinator) Leave(groupID, memberID string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	defer c.prune(groupID)
+	g, _, err := c.member(groupID, memberID)
+	if err != nil {
+		return err
+	}
+	delete(g.members, memberID)
+	c.rebalance(g)
+	return nil
+}
+
+// Validate checks whether the member can commit offsets for the group's partition in the given generation,
+// which takes the partition to be one it was handed. Consumers that aren't members commit with an empty
+// member ID, which is only accepted while the group has no members.
+func (c *Coordinator) Validate(groupID, memberID string, generation uint64, topic string, partition uint32) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	defer c.prune(groupID)
+	g, ok := c.groups[groupID]
+	if memberID == "" {
+		if ok {
+			c.expire(g, time.Now())
+		}
+		if !ok || len(g.members) == 0 {
+			return nil
+		}
+		return api.ErrUnknownMember{Group: groupID}
+	}
+	g, m, err := c.member(groupID, memberID)
+	if err != nil {
+		return err
+	}
+	if generation != g.generation {
+		return api.ErrStaleGeneration{Group: groupID, Generation: generation}
+	}
+	if !slices.Contains(m.partitions, Partition{Topic: topic, Partition: partition}) {
+		return api.ErrPartitionNotAssigned{Group: groupID, MemberID: memberID, Topic: topic, Partition: partition}
+	}
+	return nil
+}
+
+// prune deletes the group if it has no members left. It's called with c.mu held.
+func (c *Coordinator) prune(groupID string) {
+	if g, ok := c.groups[groupID]; ok && len(g.members) == 0 {
+		delete(c.groups, groupID)
+	}
+}
+
+// member returns a live member of the group. It's called with c.mu held.
+func (c *Coordinator) member(groupID, memberID string) (*group, *member, error) {
+	g, ok := c.groups[groupID]
+	if ok {
+		c.expire(g, time.Now())
+	}
+	if !ok || g.members[memberID] == nil {
+		return nil, nil, api.ErrUnknownMember{Group: groupID, MemberID: memberID}
+	}
+	return g, g.members[memberID], nil
+}
+
+// expire drops the members whose session timed out, and rebalances the group if there were any.
+// It's called with c.mu held.
+func (c *Coordinator) expire(g *group, now time.Time) {
+	var expired bool
+	for id, m := range g.members {
+		if now.Sub(m.heartbeat) > c.SessionTimeout {
+			delete(g.members, id)
+			expired = true
+		}
+	}
+	if expired {
+		c.rebalance(g)
+	}
+}
+
+// rebalance moves the group to its next generation and assigns its partitions to its members again. The
+// members are handed their partitions when they join again, see handOver. It's called with c.mu held.
+func (c *Coordinator) rebalance(g *group) {
+	g.generation++
+	members := make([]Member, 0, len(g.members))
+	partitions := make(map[string]int)
+	for _, m := range g.members {
+		members = append(members, m.Member)
+		for _, topic := range m.Topics {
+			// a topic deleted since the member joined has no partitions left to assign
+			n, err := c.Partitions(topic)
+			if err != nil {
+				n = 0
+			}
+			partitions[topic] = n
+		}
+	}
+	sort.Slice(members, func(i, j int) bool { return members[i].ID < members[j].ID })
+	assignment := strategies[g.strategy].Assign(members, partitions)
+	for id, m := range g.members {
+		m.assigned = assignment[id]
+	}
+}
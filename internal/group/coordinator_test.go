@@ -0,0 +1,137 @@
+package group
+
+import (
+	"testing"
+	"time"
+
+	"github.com/sant470/distlogs/api/v1"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCoordinator(t *testing.T) {
+	partitions := map[string]int{"clicks": 3, "orders": 2}
+
+	for scenario, fn := range map[string]func(t *testing.T, c *Coordinator, group string){
+		"rebalance on join and leave": func(t *testing.T, c *Coordinator, group string) {
+			a, err := c.Join(group, "", []string{"clicks"}, "")
+			require.NoError(t, err)
+			require.Len(t, a.Partitions, 3)
+			b, err := c.Join(group, "", []string{"clicks"}, "")
+			require.NoError(t, err)
+			require.Equal(t, a.Generation+1, b.Generation)
+			// a still has the partition b is assigned
+			require.Empty(t, b.Partitions)
+			require.Equal(t, api.ErrStaleGeneration{Group: group, Generation: a.Generation}, c.Heartbeat(group, a.MemberID, a.Generation))
+			require.Equal(t, api.ErrStaleGeneration{Group: group, Generation: a.Generation}, c.Validate(group, a.MemberID, a.Generation, "clicks", 0))
+			// a gives it up by joining again, which b finds out about
+			a, err = c.Join(group, a.MemberID, []string{"clicks"}, "")
+			require.NoError(t, err)
+			require.Equal(t, b.Generation+1, a.Generation)
+			require.Equal(t, []Partition{{"clicks", 0}, {"clicks", 1}}, a.Partitions)
+			require.NoError(t, c.Validate(group, a.MemberID, a.Generation, "clicks", 0))
+			require.Equal(t, api.ErrPartitionNotAssigned{Group: group, MemberID: a.MemberID, Topic: "clicks", Partition: 2}, c.Validate(group, a.MemberID, a.Generation, "clicks", 2))
+			require.Equal(t, api.ErrStaleGeneration{Group: group, Generation: b.Generation}, c.Heartbeat(group, b.MemberID, b.Generation))
+			b, err = c.Join(group, b.MemberID, []string{"clicks"}, "")
+			require.NoError(t, err)
+			require.Equal(t, []Partition{{"clicks", 2}}, b.Partitions)
+			// joining again without changing topics doesn't rebalance
+			a, err = c.Join(group, a.MemberID, []string{"clicks"}, "")
+			require.NoError(t, err)
+			require.Equal(t, b.Generation, a.Generation)
+			require.Equal(t, []Partition{{"clicks", 0}, {"clicks", 1}}, a.Partitions)
+
+			require.NoError(t, c.Leave(group, a.MemberID))
+			b, err = c.Join(group, b.MemberID, []string{"clicks"}, "")
+			require.NoError(t, err)
+			require.Len(t, b.Partitions, 3)
+			_, err = c.Join(group, a.MemberID, []string{"clicks"}, "")
+			require.Equal(t, api.ErrUnknownMember{Group: group, MemberID: a.MemberID}, err)
+		},
+		"session timeout": func(t *testing.T, c *Coordinator, group string) {
+			a, err := c.Join(group, "", []string{"clicks"}, "")
+			require.NoError(t, err)
+			b, err := c.Join(group, "", []string{"clicks"}, "")
+			require.NoError(t, err)
+			// only b heartbeats
+			for i := 0; i < 4; i++ {
+				time.Sleep(50 * time.Millisecond)
+				b, err = c.Join(group, b.MemberID, []string{"clicks"}, "")
+				require.NoError(t, err)
+			}
+			require.Len(t, b.Partitions, 3)
+			require.Equal(t, api.ErrUnknownMember{Group: group, MemberID: a.MemberID}, c.Heartbeat(group, a.MemberID, a.Generation))
+
+			// a group left without members is deleted, when it's touched or another group's joined
+			require.NoError(t, c.Leave(group, b.MemberID))
+			require.NotContains(t, c.groups, group)
+			_, err = c.Join(group, "", []string{"clicks"}, "")
+			require.NoError(t, err)
+			_, err = c.Join("shipping", "", []string{"clicks"}, "")
+			require.NoError(t, err)
+			time.Sleep(150 * time.Millisecond)
+			_, err = c.Join("shipping", "", []string{"clicks"}, "")
+			require.NoError(t, err)
+			require.NotContains(t, c.groups, group)
+		},
+		"commits from outside the group": func(t *testing.T, c *Coordinator, group string) {
+			require.NoError(t, c.Validate(group, "", 0, "clicks", 0))
+			a, err := c.Join(group, "", []string{"clicks"}, "")
+			require.NoError(t, err)
+			require.Equal(t, api.ErrUnknownMember{Group: group}, c.Validate(group, "", 0, "clicks", 0))
+			require.NoError(t, c.Leave(group, a.MemberID))
+			require.NoError(t, c.Validate(group, "", 0, "clicks", 0))
+		},
+		"strategies": func(t *testing.T, c *Coordinator, group string) {
+			_, err := c.Join(group, "", []string{"clicks"}, "sticky")
+			require.Equal(t, api.ErrUnknownStrategy{Strategy: "sticky"}, err)
+			_, err = c.Join(group, "", []string{"payments"}, "")
+			require.Equal(t, api.ErrTopicNotFound{Topic: "payments"}, err)
+			a, err := c.Join(group, "", []string{"clicks", "orders"}, "roundrobin")
+			require.NoError(t, err)
+			_, err = c.Join(group, "", []string{"clicks"}, "range")
+			require.Equal(t, api.ErrInconsistentStrategy{Group: group, Strategy: "range", GroupStrategy: "roundrobin"}, err)
+			b, err := c.Join(group, "", []string{"clicks", "orders"}, "roundrobin")
+			require.NoError(t, err)
+			a, err = c.Join(group, a.MemberID, []string{"clicks", "orders"}, "roundrobin")
+			require.NoError(t, err)
+			b, err = c.Join(group, b.MemberID, []string{"clicks", "orders"}, "roundrobin")
+			require.NoError(t, err)
+			require.Equal(t, []Partition{{"clicks", 0}, {"clicks", 2}, {"orders", 1}}, a.Partitions)
+			require.Equal(t, []Partition{{"clicks", 1}, {"orders", 0}}, b.Partitions)
+		},
+	} {
+		t.Run(scenario, func(t *testing.T) {
+			c := New(Config{
+				SessionTimeout: 100 * time.Millisecond,
+				Partitions: func(topic string) (int, error) {
+					n, ok := partitions[topic]
+					if !ok {
+						return 0, api.ErrTopicNotFound{Topic: topic}
+					}
+					return n, nil
+				},
+			})
+			fn(t, c, "billing")
+		})
+	}
+}
+
+func TestStrategies(t *testing.T) {
+	members := []Member{
+		{ID: "a", Topics: []string{"clicks", "orders"}},
+		{ID: "b", Topics: []string{"clicks"}},
+		{ID: "c", Topics: []string{"clicks", "orders"}},
+	}
+	partitions := map[string]int{"clicks": 4, "orders": 3}
+
+	require.Equal(t, map[string][]Partition{
+		"a": {{"clicks", 0}, {"clicks", 1}, {"orders", 0}, {"orders", 1}},
+		"b": {{"clicks", 2}},
+		"c": {{"clicks", 3}, {"orders", 2}},
+	}, Range{}.Assign(members, partitions))
+	require.Equal(t, map[string][]Partition{
+		"a": {{"clicks", 0}, {"clicks", 3}, {"orders", 1}},
+		"b": {{"clicks", 1}},
+		"c": {{"clicks", 2}, {"orders", 0}, {"orders", 2}},
+	}, RoundRobin{}.Assign(members, partitions))
+}
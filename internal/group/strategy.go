@@ -0,0 +1,101 @@
+package group
+
+import (
+	"slices"
+	"sort"
+)
+
+// Member is a group member as strategies see it.
+type Member struct {
+	ID string
+	// Topics are the topics the member consumes
+	Topics []string
+}
+
+type Partition struct {
+	Topic     string
+	Partition uint32
+}
+
+// Strategy assigns a group's partitions to its members.
+type Strategy interface {
+	// Assign spreads the partitions of each topic, partitions[topic] of them, over the members consuming it,
+	// given sorted by ID, and returns each member's partitions.
+	Assign(members []Member, partitions map[string]int) map[string][]Partition
+}
+
+var strategies = map[string]Strategy{
+	"range":      Range{},
+	"roundrobin": RoundRobin{},
+}
+
+// Range gives each member of a topic a contiguous range of its partitions, the first members one more when
+// they don't divide evenly.
+type Range struct{}
+
+func (Range) Assign(members []Member, partitions map[string]int) map[string][]Partition {
+	assignment := make(map[string][]Partition)
+	for _, topic := range topics(partitions) {
+		consumers := consumersOf(members, topic)
+		if len(consumers) == 0 {
+			continue
+		}
+		n := partitions[topic]
+		per, extra := n/len(consumers), n%len(consumers)
+		var p uint32
+		for i, m := range consumers {
+			count := per
+			if i < extra {
+				count++
+			}
+			for ; count > 0; count-- {
+				assignment[m.ID] = append(assignment[m.ID], Partition{Topic: topic, Partition: p})
+				p++
+			}
+		}
+	}
+	return assignment
+}
+
+// RoundRobin deals every topic's partitions out to the members in turn, skipping the members that don't
+// consume the topic, so the members end up with a partition count at most one apart when they all consume
+// the same topics.
+type RoundRobin struct{}
+
+func (RoundRobin) Assign(members []Member, partitions map[string]int) map[string][]Partition {
+	assignment := make(map[string][]Partition)
+	next := 0
+	for _, topic := range topics(partitions) {
+		if len(consumersOf(members, topic)) == 0 {
+			continue
+		}
+		for p := 0; p < partitions[topic]; p++ {
+			for !slices.Contains(members[next%len(members)].Topics, topic) {
+				next++
+			}
+			m := members[next%len(members)]
+			assignment[m.ID] = append(assignment[m.ID], Partition{Topic: topic, Partition: uint32(p)})
+			next++
+		}
+	}
+	return assignment
+}
+
+func topics(partitions map[string]int) []string {
+	topics := make([]string, 0, len(partitions))
+	for topic := range partitions {
+		topics = append(topics, topic)
+	}
+	sort.Strings(topics)
+	return topics
+}
+
+func consumersOf(members []Member, topic string) []Member {
+	var consumers []Member
+	for _, m := range members {
+		if slices.Contains(m.Topics, topic) {
+			consumers = append(consumers, m)
+		}
+	}
+	return consumers
+}
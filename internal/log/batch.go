@@ -0,0 +1,166 @@
+/*
+	With compression on, the records of the appends committed together are written to the store as a single
+	batch frame, as long as they make up Compression.MinBatchBytes: compressing a small append on its own
+	would only grow the store. Compression.Linger has the committer wait for more appends to fill a batch
+	before it commits. The records of a Log.AppendBatch are always written as a single batch frame, compressed or not,
+	so that a crash keeps either all of them or none: the frame's checksum tells a torn batch apart.
+	The batch's payload starts with the ID of the codec that compressed it, followed by the compressed
+	records, each prefixed with its length. Every record in a batch has its own index entry, all pointing at
+	the batch, unless the index is sparse; reading one decompresses the whole batch. Since each batch names
+	its codec, a log can switch codecs, or turn compression on or off, over its lifetime.
+*/
+
+package log
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/sant470/distlogs/api/v1"
+)
+
+// Codec compresses the batches of records written to the store.
+type Codec interface {
+	// ID identifies the codec in the batches it compresses, so it has to be unique and never change.
+	ID() byte
+	Name() string
+	Compress(p []byte) ([]byte, error)
+	Decompress(p []byte) ([]byte, error)
+}
+
+var (
+	// Gzip compresses batches with compress/gzip.
+	Gzip Codec = gzipCodec{}
+	// uncompressed frames batches that aren't compressed.
+	uncompressed Codec = noCodec{}
+)
+
+var (
+	codecsMu sync.RWMutex
+	codecs   = map[byte]Codec{uncompressed.ID(): uncompressed, Gzip.ID(): Gzip}
+)
+
+// RegisterCodec makes a codec, e.g. zstd or snappy, available for reading batches. NewLog registers the
+// codec it's configured with, so this is only needed for codecs a log used to be configured with.
+func RegisterCodec(c Codec) error {
+	codecsMu.Lock()
+	defer codecsMu.Unlock()
+	if registered, ok := codecs[c.ID()]; ok && registered.Name() != c.Name() {
+		return fmt.Errorf("codec id %d is already taken by %s", c.ID(), registered.Name())
+	}
+	codecs[c.ID()] = c
+	return nil
+}
+
+func codec(id byte) (Codec, error) {
+	codecsMu.RLock()
+	defer codecsMu.RUnlock()
+	c, ok := codecs[id]
+	if !ok {
+		return nil, api.ErrUnknownCodec{ID: id}
+	}
+	return c, nil
+}
+
+func codecNamed(name string) (Codec, error) {
+	codecsMu.RLock()
+	defer codecsMu.RUnlock()
+	for _, c := range codecs {
+		if c.Name() == name {
+			return c, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown codec %s", name)
+}
+
+// encodeBatch compresses the marshalled records into a batch frame's payload.
+func encodeBatch(c Codec, ps [][]byte) ([]byte, error) {
+	var b []byte
+	for _, p := range ps {
+		b = binary.AppendUvarint(b, uint64(len(p)))
+		b = append(b, p...)
+	}
+	compressed, err := c.Compress(b)
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte{c.ID()}, compressed...), nil
+}
+
+// decodeBatch returns the marshalled records of a batch frame's payload.
+func decodeBatch(p []byte) ([][]byte, error) {
+	if len(p) == 0 {
+		return nil, io.ErrUnexpectedEOF
+	}
+	c, err := codec(p[0])
+	if err != nil {
+		return nil, err
+	}
+	b, err := c.Decompress(p[1:])
+	if err != nil {
+		return nil, err
+	}
+	var ps [][]byte
+	for len(b) > 0 {
+		size, n := binary.Uvarint(b)
+		if n <= 0 || uint64(len(b)-n) < size {
+			return nil, io.ErrUnexpectedEOF
+		}
+		ps = append(ps, b[n:n+int(size)])
+		b = b[n+int(size):]
+	}
+	return ps, nil
+}
+
+type noCodec struct{}
+
+func (noCodec) ID() byte {
+	return 0
+}
+
+func (noCodec) Name() string {
+	return "none"
+}
+
+func (noCodec) Compress(p []byte) ([]byte, error) {
+	return p, nil
+}
+
+func (noCodec) Decompress(p []byte) ([]byte, error) {
+	return p, nil
+}
+
+type gzipCodec struct{}
+
+func (gzipCodec) ID() byte {
+	return 1
+}
+
+func (gzipCodec) Name() string {
+	return "gzip"
+}
+
+func (gzipCodec) Compress(p []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	if _, err := w.Write(p); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (gzipCodec) Decompress(p []byte) ([]byte, error) {
+	r, err := gzip.NewReader(bytes.NewReader(p))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return io.ReadAll(r)
+}
@@ -0,0 +1,285 @@
+/*
+	Compaction keeps only the latest record for each key in the sealed segments, for logs used as the source
+	of truth for keyed state. Records without a key are always kept. A record with a key and no value is a
+	tombstone: it deletes the key, and is itself dropped once it's older than Compaction.TombstoneRetention,
+	which gives consumers time to see it. The active segment is never compacted.
+	Records of aborted transactions are dropped, and the transactions forgotten, while records from the last
+	stable offset on are left alone since their transactions may still be aborted.
+	Records keep their offsets, so compacted segments have gaps in them, and reading an offset compaction
+	removed returns the next record after it.
+	Compaction only reads the records appended since it last ran for the latest offset of each key, since
+	the ones it's already compacted hold each key once, and only runs once they make up
+	Compaction.MinDirtyRatio of the sealed segments, or a tombstone it kept is past its retention, so
+	rewriting the segments costs about as much as the records appended. The log's compacted in full when
+	it's set up again.
+	A segment with records to drop is rewritten to a directory next to the log, then swapped in: its stale
+	indexes are removed first, then the new store replaces the old one and the new indexes follow. A crash
+	at any point leaves either store with indexes that match it or none, which recovery rebuilds.
+*/
+
+package log
+
+import (
+	"io"
+	"os"
+	"path"
+	"slices"
+	"time"
+
+	"github.com/sant470/distlogs/api/v1"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/proto"
+)
+
+// compactingDir holds the segments being rewritten, under the log's directory.
+const compactingDir = "compacting"
+
+// Compact compacts the sealed segments down to the latest record per key.
+func (l *Log) Compact() error {
+	l.compactMu.Lock()
+	defer l.compactMu.Unlock()
+	l.mu.Lock()
+	segments := l.loadSegments()
+	sealed := slices.Clone(segments[:len(segments)-1])
+	// the segments are on the list and so can't have been released yet
+	for _, s := range sealed {
+		s.acquire()
+	}
+	l.mu.Unlock()
+	defer func() {
+		for _, s := range sealed {
+			l.releaseSegment(s)
+		}
+	}()
+
+	lso := l.LastStableOffset()
+	// records from the last stable offset on are left for later, their transactions may still be aborted
+	end := min(lso, segments[len(segments)-1].baseOffset)
+	var total, dirty uint64
+	for _, s := range sealed {
+		total += s.store.size
+		if s.nextOffset.Load() > l.compactedTo && s.baseOffset < end {
+			dirty += s.store.size
+		}
+	}
+	now := time.Now()
+	if float64(dirty) < float64(total)*l.Config.Compaction.MinDirtyRatio &&
+		(l.tombstonesDue == 0 || now.UnixNano() < l.tombstonesDue) {
+		return nil
+	}
+	aborted := l.loadAbortedTxns()
+	// the records compacted already hold each key once, the ones appended since have the latest
+	latest := make(map[string]uint64)
+	for _, s := range sealed {
+		if s.nextOffset.Load() <= l.compactedTo {
+			continue
+		}
+		if err := s.scan(func(record *api.Record) error {
+			if len(record.Key) > 0 && record.Offset >= l.compactedTo && record.Offset < lso &&
+				!inAbortedTxn(record, aborted) {
+				latest[string(record.Key)] = record.Offset
+			}
+			return nil
+		}); err != nil {
+			return err
+		}
+	}
+	retention := l.Config.Compaction.TombstoneRetention
+	horizon := now.Add(-retention).UnixNano()
+	// the oldest tombstone kept, which the next compaction drops once it's past its retention
+	var oldestTombstone int64
+	keep := func(record *api.Record) bool {
+		if record.Offset >= lso {
+			return true
+		}
+		if inAbortedTxn(record, aborted) {
+			return false
+		}
+		if len(record.Key) == 0 {
+			return true
+		}
+		if off, ok := latest[string(record.Key)]; ok && off != record.Offset {
+			return false
+		}
+		if len(record.Value) > 0 {
+			return true
+		}
+		if record.Timestamp <= horizon {
+			return false
+		}
+		if oldestTombstone == 0 || record.Timestamp < oldestTombstone {
+			oldestTombstone = record.Timestamp
+		}
+		return true
+	}
+	for _, s := range sealed {
+		if err := l.compactSegment(s, keep); err != nil {
+			return err
+		}
+	}
+	l.compactedTo, l.tombstonesDue = max(l.compactedTo, end), 0
+	if oldestTombstone != 0 {
+		l.tombstonesDue = oldestTombstone + int64(retention)
+	}
+	// the records of the aborted transactions that ended in the sealed segments are gone
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.pruneAbortedTxns(min(lso, segments[len(segments)-1].baseOffset)) {
+		l.publishTxns()
+	}
+	return nil
+}
+
+// compactSegment rewrites s with only the records to keep, if there are any to drop.
+func (l *Log) compactSegment(s *segment, keep func(*api.Record) bool) error {
+	var dropped uint64
+	if err := s.scan(func(record *api.Record) error {
+		if !keep(record) {
+			dropped++
+		}
+		return nil
+	}); err != nil || dropped == 0 {
+		return err
+	}
+	dir := path.Join(l.Dir, compactingDir)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+	cs, err := newSegment(dir, s.baseOffset, l.Config)
+	if err != nil {
+		return err
+	}
+	// the records kept are written in groups, so they're batched like appends when the log compresses
+	var (
+		records []*api.Record
+		ps      [][]byte
+	)
+	flush := func() error {
+		if len(records) == 0 {
+			return nil
+		}
+		err := cs.write(records, ps, nil)
+		records, ps = nil, nil
+		return err
+	}
+	if err = s.scan(func(record *api.Record) error {
+		if !keep(record) {
+			return nil
+		}
+		p, err := proto.Marshal(record)
+		if err != nil {
+			return err
+		}
+		records, ps = append(records, record), append(ps, p)
+		if len(records) < maxGroup {
+			return nil
+		}
+		return flush()
+	}); err == nil {
+		err = flush()
+	}
+	if err != nil {
+		cs.Close()
+		return err
+	}
+	if err = cs.Sync(); err != nil {
+		cs.Close()
+		return err
+	}
+	if err = cs.Close(); err != nil {
+		return err
+	}
+	if err = l.swap(s, dir); err != nil {
+		return err
+	}
+	l.logger.Info(
+		"compacted segment",
+		zap.String("dir", l.Dir),
+		zap.Uint64("base_offset", s.baseOffset),
+		zap.Uint64("dropped_records", dropped),
+	)
+	return nil
+}
+
+// swap replaces s with the compacted segment written to dir, unless it's been deleted in the meantime.
+func (l *Log) swap(s *segment, dir string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	segments := l.loadSegments()
+	i := slices.Index(segments, s)
+	if i < 0 {
+		return nil
+	}
+	for _, name := range []string{s.index.Name(), s.timeIndex.Name()} {
+		if err := os.Remove(name); err != nil {
+			return err
+		}
+	}
+	for _, name := range []string{s.store.Name(), s.index.Name(), s.timeIndex.Name()} {
+		if err := os.Rename(path.Join(dir, path.Base(name)), name); err != nil {
+			return err
+		}
+	}
+	cs, err := newSegment(l.Dir, s.baseOffset, l.Config)
+	if err != nil {
+		return err
+	}
+	segments = slices.Clone(segments)
+	segments[i] = cs
+	l.publish(segments)
+	// readers still holding the old segment keep reading its files, which are gone from the directory
+	s.replaced.Store(true)
+	return s.release()
+}
+
+// scan calls fn with every record in the segment, in offset order.
+func (s *segment) scan(fn func(*api.Record) error) error {
+	for off := s.baseOffset; ; {
+		record, err := s.Read(off)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err = fn(record); err != nil {
+			return err
+		}
+		off = record.Offset + 1
+	}
+}
+
+func (l *Log) startCompactor() {
+	// a log set up again is compacted in full
+	l.compactedTo, l.tombstonesDue = 0, 0
+	if !l.Config.Compaction.Enabled {
+		return
+	}
+	l.stopCompact = make(chan struct{})
+	l.compactDone = make(chan struct{})
+	go func() {
+		defer close(l.compactDone)
+		ticker := time.NewTicker(l.Config.Compaction.Interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-l.stopCompact:
+				return
+			case <-ticker.C:
+				if err := l.Compact(); err != nil {
+					l.logger.Error("failed to compact", zap.String("dir", l.Dir), zap.Error(err))
+				}
+			}
+		}
+	}()
+}
+
+func (l *Log) stopCompactor() {
+	if l.stopCompact == nil {
+		return
+	}
+	close(l.stopCompact)
+	<-l.compactDone
+	l.stopCompact = nil
+}
@@ -1,9 +1,119 @@
 package log
 
+import (
+	"encoding/json"
+	"time"
+)
+
 type Config struct {
 	Segment struct {
+		// MaxStoreBytes rolls the segment once its store has grown past it.
 		MaxStoreBytes uint64
-		MaxIndexBytes uint64
-		InitialOffset uint64
+		// MaxAge rolls the segment once its first record is older than it, never if left zero.
+		MaxAge time.Duration
+		// IndexGrowBytes is how much the index file grows by whenever it fills up, 64KiB if left zero.
+		IndexGrowBytes uint64
+		// IndexIntervalBytes and IndexIntervalRecords make the index sparse: a frame only gets an index entry
+		// once that many store bytes or records went by since the last one. Every record gets an entry if
+		// both are left zero.
+		IndexIntervalBytes   uint64
+		IndexIntervalRecords uint64
+		InitialOffset        uint64
+		// TimeIndexIntervalBytes is roughly how much of the store goes between time index entries,
+		// 4KiB if left zero.
+		TimeIndexIntervalBytes uint64
+	}
+	Durability struct {
+		Mode SyncMode
+		// Interval is how often SyncInterval fsyncs, 10ms if left zero.
+		Interval time.Duration
+		// Bytes is how many appended bytes make SyncBytes fsync, 1MiB if left zero.
+		Bytes uint64
+	}
+	Retention struct {
+		// MaxAge deletes segments whose newest record is older than it, none if left zero.
+		MaxAge time.Duration
+		// MaxBytes deletes the oldest segments while the stores add up to more than it, none if left zero.
+		MaxBytes uint64
+		// MinSegments is how many segments are kept whatever their age or size, the active one included.
+		MinSegments int
+		// CheckInterval is how often retention is enforced, every minute if left zero.
+		CheckInterval time.Duration
+	}
+	Compression struct {
+		// Codec compresses the records of the appends committed together into a batch, none if left nil.
+		// It's stored by name in a config's JSON.
+		Codec Codec `json:"-"`
+		// MinBatchBytes is how big a batch's marshalled records have to be for it to be compressed, 1KiB if
+		// left zero. Smaller batches are written as they are, compressing them would mostly add overhead.
+		MinBatchBytes uint64
+		// Linger is how long the committer waits for more appends to make up MinBatchBytes before it
+		// commits the ones it has, none if left zero. Without it, only the appends that queue up while the
+		// committer's busy are batched.
+		Linger time.Duration
+	}
+	Compaction struct {
+		// Enabled compacts sealed segments down to the latest record per key, see compaction.go.
+		Enabled bool
+		// TombstoneRetention is how long compaction keeps a tombstone after it was appended, a day if left zero.
+		TombstoneRetention time.Duration
+		// Interval is how often compaction runs, every minute if left zero.
+		Interval time.Duration
+		// MinDirtyRatio is the share of the sealed segments' bytes that have to be appended since the last
+		// compaction for the next one to run, half if left zero.
+		MinDirtyRatio float64
+	}
+}
+
+// MarshalJSON encodes the config with its codec by name.
+func (c Config) MarshalJSON() ([]byte, error) {
+	type plain Config
+	var codec string
+	if c.Compression.Codec != nil {
+		codec = c.Compression.Codec.Name()
 	}
+	return json.Marshal(struct {
+		plain
+		Codec string `json:",omitempty"`
+	}{plain(c), codec})
 }
+
+// UnmarshalJSON decodes a config MarshalJSON encoded. Its codec has to be registered.
+func (c *Config) UnmarshalJSON(b []byte) error {
+	type plain Config
+	v := struct {
+		*plain
+		Codec string `json:",omitempty"`
+	}{plain: (*plain)(c)}
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	if v.Codec == "" {
+		return nil
+	}
+	codec, err := codecNamed(v.Codec)
+	if err != nil {
+		return err
+	}
+	c.Compression.Codec = codec
+	return nil
+}
+
+// SyncMode decides when appends get fsynced, and so what an offset returned by Log.Append guarantees.
+type SyncMode int
+
+const (
+	// SyncOS hands every append to the OS before acknowledging it and leaves writing it to disk up
+	// to the OS. An acknowledged offset survives the process crashing, but not the machine.
+	SyncOS SyncMode = iota
+	// SyncAlways fsyncs the store and index before acknowledging each append.
+	// An acknowledged offset is on disk.
+	SyncAlways
+	// SyncInterval fsyncs every Durability.Interval and holds appends until the sync that covers them,
+	// committing every append made during the interval together. An acknowledged offset is on disk.
+	SyncInterval
+	// SyncBytes fsyncs once Durability.Bytes have been appended since the last sync and otherwise
+	// acknowledges appends once the OS has them. At most Durability.Bytes of acknowledged appends are
+	// lost if the machine crashes.
+	SyncBytes
+)
@@ -0,0 +1,105 @@
+/*
+	The durability policy decides when appended records are committed to stable storage, and so what an
+	offset returned by Log.Append guarantees. Appends always go through the store's buffer; depending on the
+	policy the log then hands them to the OS, fsyncs them straight away, or lets a background syncer commit
+	everything appended during an interval with a single fsync while the appenders wait for it.
+*/
+
+package log
+
+import "time"
+
+// syncBatch is the set of appends committed together by one periodic sync.
+type syncBatch struct {
+	done chan struct{}
+	err  error
+}
+
+func newSyncBatch() *syncBatch {
+	return &syncBatch{done: make(chan struct{})}
+}
+
+// afterAppend applies the durability policy to the n bytes just appended to s. It's called with l.mu
+// held and returns the batch the caller has to wait on before acknowledging the append, if any.
+func (l *Log) afterAppend(s *segment, n uint64) (*syncBatch, error) {
+	switch l.Config.Durability.Mode {
+	case SyncAlways:
+		return nil, s.Sync()
+	case SyncInterval:
+		l.unsynced += n
+		return l.batch, s.store.Flush()
+	case SyncBytes:
+		l.unsynced += n
+		if l.unsynced >= l.Config.Durability.Bytes {
+			l.unsynced = 0
+			return nil, s.Sync()
+		}
+	}
+	return nil, s.store.Flush()
+}
+
+// beforeRoll commits what's still unsynced in the segment about to be sealed, since the syncs to come
+// only cover the active segment. Appends waiting on the current batch are covered too, so they're
+// released straight away. It's called with l.mu held.
+func (l *Log) beforeRoll(s *segment) error {
+	if l.unsynced == 0 {
+		return nil
+	}
+	l.unsynced = 0
+	err := s.Sync()
+	if l.batch != nil {
+		l.batch.err = err
+		close(l.batch.done)
+		l.batch = newSyncBatch()
+	}
+	return err
+}
+
+func (l *Log) startSyncer() {
+	if l.Config.Durability.Mode != SyncInterval {
+		return
+	}
+	l.batch = newSyncBatch()
+	l.stopSync = make(chan struct{})
+	l.syncDone = make(chan struct{})
+	go func() {
+		defer close(l.syncDone)
+		ticker := time.NewTicker(l.Config.Durability.Interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-l.stopSync:
+				return
+			case <-ticker.C:
+				l.commitBatch()
+			}
+		}
+	}()
+}
+
+// stopSyncer stops the background syncer and commits whatever appends are still waiting on it.
+func (l *Log) stopSyncer() {
+	if l.stopSync == nil {
+		return
+	}
+	close(l.stopSync)
+	<-l.syncDone
+	l.stopSync = nil
+	l.commitBatch()
+}
+
+// commitBatch fsyncs the active segment and releases the appends waiting on the current batch.
+// The fsync runs outside the lock so appends can start filling the next batch meanwhile.
+func (l *Log) commitBatch() {
+	l.mu.Lock()
+	if l.unsynced == 0 {
+		l.mu.Unlock()
+		return
+	}
+	batch, s := l.batch, l.activeSegment
+	l.batch = newSyncBatch()
+	l.unsynced = 0
+	l.mu.Unlock()
+	batch.err = s.Sync()
+	close(batch.done)
+}
@@ -0,0 +1,165 @@
+/*
+	Appends can expect the log to be at an offset, or the latest record with their key to be at one, for
+	optimistic concurrency: a writer reads what it needs from the log, decides what to append from it, and
+	appends expecting nothing was appended since. If another writer got there first the append fails with
+	api.ErrConflict, and the writer reads again and retries. Expectations are checked in the committer,
+	where appends are put in order, so two writers expecting the same offset can't both succeed.
+	The latest offset of each key is only kept once an append expects one: the first such append reads the
+	log to find them before it's handed to the committer, which only catches up with the records appended
+	meanwhile, and keeps them up to date from there on. A transaction's records only count once it's
+	committed, those of an aborted one never do.
+*/
+
+package log
+
+import (
+	"bytes"
+
+	"github.com/sant470/distlogs/api/v1"
+)
+
+// Expectation makes an append conditional on what's been appended before it.
+type Expectation struct {
+	// NextOffset, if set, is the offset the record is expected to be appended at.
+	NextOffset *uint64
+	// KeyOffset, if set, is the offset of the latest record with the record's key, or -1 if there's none.
+	KeyOffset *int64
+}
+
+// keyIndex has the latest offset of each key, and the keys of the records of the transactions still open,
+// by producer, until they end.
+type keyIndex struct {
+	offsets map[string]uint64
+	txns    map[uint64]map[string]uint64
+}
+
+func newKeyIndex() *keyIndex {
+	return &keyIndex{offsets: make(map[string]uint64), txns: make(map[uint64]map[string]uint64)}
+}
+
+// observe applies the record to the index.
+func (k *keyIndex) observe(record *api.Record) {
+	id := record.ProducerId
+	switch {
+	case record.Control == api.Control_COMMIT:
+		for key, off := range k.txns[id] {
+			// a record appended after the transaction's stays the latest
+			if latest, ok := k.offsets[key]; !ok || off > latest {
+				k.offsets[key] = off
+			}
+		}
+		delete(k.txns, id)
+	case record.Control == api.Control_ABORT:
+		delete(k.txns, id)
+	case len(record.Key) == 0:
+	case record.Transactional && id != 0:
+		if k.txns[id] == nil {
+			k.txns[id] = make(map[string]uint64)
+		}
+		k.txns[id][string(record.Key)] = record.Offset
+	default:
+		k.offsets[string(record.Key)] = record.Offset
+	}
+}
+
+// AppendExpect appends the record if the expectation holds, see Append. It fails with api.ErrConflict
+// if it doesn't.
+func (l *Log) AppendExpect(record *api.Record, expect Expectation) (uint64, error) {
+	if expect.KeyOffset != nil {
+		if len(record.Key) == 0 {
+			return 0, api.ErrExpectedKey{}
+		}
+		if err := l.loadKeyOffsets(); err != nil {
+			return 0, err
+		}
+	}
+	return l.submit([]*api.Record{record}, expect)
+}
+
+// checkExpectation checks the append's expectation, given the appends pending before it and the offset
+// it goes to. It's called with l.mu held.
+func (l *Log) checkExpectation(req *appendRequest, pending []*appendRequest, next uint64) error {
+	expect := req.expect
+	if expect.NextOffset != nil && *expect.NextOffset != next {
+		return api.ErrConflict{Expected: int64(*expect.NextOffset), Actual: int64(next)}
+	}
+	if expect.KeyOffset == nil {
+		return nil
+	}
+	key := req.records[0].Key
+	actual := l.keyOffset(key, pending, next)
+	if actual != *expect.KeyOffset {
+		return api.ErrConflict{Key: key, Expected: *expect.KeyOffset, Actual: actual}
+	}
+	return nil
+}
+
+// keyOffset returns the offset of the latest record with the key, the appends pending before next included,
+// or -1 if there's none. It's called with l.mu held, once the keys are loaded.
+func (l *Log) keyOffset(key []byte, pending []*appendRequest, next uint64) int64 {
+	// the pending appends go to the offsets right before next
+	off := next
+	for i := len(pending) - 1; i >= 0; i-- {
+		records := pending[i].records
+		for j := len(records) - 1; j >= 0; j-- {
+			off--
+			if !records[j].Transactional && bytes.Equal(records[j].Key, key) {
+				return int64(off)
+			}
+		}
+	}
+	// the keys are only missing if the log was reset after the append loaded them, it's empty since
+	if l.keys == nil {
+		return -1
+	}
+	if off, ok := l.keys.offsets[string(key)]; ok {
+		return int64(off)
+	}
+	return -1
+}
+
+// loadKeyOffsets finds the latest offset of each key in the log, unless they're kept already. The log's
+// read without l.mu held, appends carrying on, and the records appended meanwhile are caught up with
+// under it.
+func (l *Log) loadKeyOffsets() error {
+	if l.keysLoaded.Load() {
+		return nil
+	}
+	l.loadKeysMu.Lock()
+	defer l.loadKeysMu.Unlock()
+	if l.keysLoaded.Load() {
+		return nil
+	}
+	keys := newKeyIndex()
+	it := l.Iterator(0)
+	for it.Next() {
+		keys.observe(it.Record())
+	}
+	if err := it.Err(); err != nil {
+		return err
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for it.Next() {
+		keys.observe(it.Record())
+	}
+	if err := it.Err(); err != nil {
+		return err
+	}
+	l.keys = keys
+	l.keysLoaded.Store(true)
+	return nil
+}
+
+// recordKeys keeps the latest offsets of the keys up to date with the appended records, once they're kept.
+// It's called with l.mu held.
+func (l *Log) recordKeys(appended []*appendRequest) {
+	if l.keys == nil {
+		return
+	}
+	for _, req := range appended {
+		for _, record := range req.records {
+			l.keys.observe(record)
+		}
+	}
+}
@@ -0,0 +1,55 @@
+/*
+	Fetches read a batch of records in one go for consumers catching up, instead of one record per read.
+	A batch is bounded by a number of records and by their encoded size, and comes with the log's low and
+	high watermarks, so consumers know how far behind they are. Fetches read uncommitted, read committed
+	consumers go through ReadCommitted.
+*/
+
+package log
+
+import "github.com/sant470/distlogs/api/v1"
+
+// Fetch reads the records from off on, up to maxRecords of them and maxBytes of their encoded size, no
+// limit if left zero, see Iterator. It returns no records if off is the log's next offset, and
+// api.ErrOffsetOutOfRange if off is below the log's lowest offset or past its next one.
+func (l *Log) Fetch(off uint64, maxRecords int, maxBytes uint64) ([]*api.Record, error) {
+	low, high := l.Watermarks()
+	if off < low || off > high {
+		return nil, api.ErrOffsetOutOfRange{Offset: off}
+	}
+	it := l.Iterator(off)
+	it.MaxRecords, it.MaxBytes = maxRecords, maxBytes
+	var records []*api.Record
+	for it.Next() {
+		records = append(records, it.Record())
+	}
+	return records, it.Err()
+}
+
+// Watermarks returns the log's low watermark, its lowest offset, and its high watermark, the next offset to
+// be appended.
+func (l *Log) Watermarks() (low, high uint64) {
+	segments := l.loadSegments()
+	return segments[0].baseOffset, segments[len(segments)-1].nextOffset.Load()
+}
+
+// Fetch reads a batch of records from off on from the topic's partition, see Log.Fetch.
+func (m *LogManager) Fetch(
+	topic string, partition uint32, off uint64, maxRecords int, maxBytes uint64,
+) ([]*api.Record, error) {
+	l, err := m.Partition(topic, partition)
+	if err != nil {
+		return nil, err
+	}
+	return l.Fetch(off, maxRecords, maxBytes)
+}
+
+// Watermarks returns the topic's partition's low and high watermarks, see Log.Watermarks.
+func (m *LogManager) Watermarks(topic string, partition uint32) (low, high uint64, err error) {
+	l, err := m.Partition(topic, partition)
+	if err != nil {
+		return 0, 0, err
+	}
+	low, high = l.Watermarks()
+	return low, high, nil
+}
@@ -0,0 +1,232 @@
+/*
+	Appends go through a single committer goroutine. Callers hand their record to the committer and wait;
+	the committer takes every append that queued up while it was busy, writes their records to the active
+	segment with one store write, applies the durability policy once for all of them, and hands each caller
+	back its own offset. Under load this amortises the lock, the write and above all the fsync over every
+	concurrent producer, gRPC ProduceStream handlers included.
+	A batch from Log.AppendBatch is committed as a unit: its records go to the same segment in a single
+	frame and become visible together, even if that takes the segment past MaxStoreBytes.
+*/
+
+package log
+
+import (
+	"errors"
+	"runtime"
+	"time"
+
+	"github.com/sant470/distlogs/api/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+// maxGroup caps how many appends are committed together.
+const maxGroup = 1024
+
+var (
+	// ErrClosed is returned for appends and reads once the log's closed.
+	ErrClosed = errors.New("log is closed")
+	// ErrEmptyBatch is returned by Log.AppendBatch for a batch without records.
+	ErrEmptyBatch = errors.New("batch has no records")
+)
+
+type appendRequest struct {
+	records []*api.Record
+	expect  Expectation
+	done    chan struct{}
+	// set by the committer before done is closed
+	off   uint64
+	batch *syncBatch
+	err   error
+	// a retry of an append pending in the same group is acknowledged with the original, delta records on
+	original *appendRequest
+	delta    uint64
+}
+
+// submit queues the records for the committer and waits until they're acknowledged.
+func (l *Log) submit(records []*api.Record, expect Expectation) (uint64, error) {
+	req := &appendRequest{records: records, expect: expect, done: make(chan struct{})}
+	select {
+	case l.appends <- req:
+	case <-l.stopCommit:
+		return 0, ErrClosed
+	}
+	<-req.done
+	if req.err != nil {
+		return 0, req.err
+	}
+	if req.batch != nil {
+		<-req.batch.done
+		if req.batch.err != nil {
+			return 0, req.batch.err
+		}
+	}
+	return req.off, nil
+}
+
+func (l *Log) startCommitter() {
+	l.appends = make(chan *appendRequest)
+	l.stopCommit = make(chan struct{})
+	l.commitDone = make(chan struct{})
+	go func() {
+		defer close(l.commitDone)
+		for {
+			select {
+			case <-l.stopCommit:
+				return
+			case req := <-l.appends:
+				group := []*appendRequest{req}
+				// let the producers we just acknowledged queue their next appends before draining,
+				// otherwise on few cores every group ends up holding a single append
+				runtime.Gosched()
+			drain:
+				for len(group) < maxGroup {
+					select {
+					case req := <-l.appends:
+						group = append(group, req)
+					default:
+						break drain
+					}
+				}
+				l.commit(l.linger(group))
+			}
+		}
+	}()
+}
+
+// linger waits up to Compression.Linger for more appends to join the group, until their records make up
+// Compression.MinBatchBytes, so they're compressed together. The group's committed as it is once the log's
+// closing.
+func (l *Log) linger(group []*appendRequest) []*appendRequest {
+	c := l.Config.Compression
+	if c.Codec == nil || c.Linger == 0 {
+		return group
+	}
+	var size uint64
+	add := func(req *appendRequest) {
+		for _, record := range req.records {
+			size += uint64(proto.Size(record))
+		}
+	}
+	for _, req := range group {
+		add(req)
+	}
+	timer := time.NewTimer(c.Linger)
+	defer timer.Stop()
+	for size < c.MinBatchBytes && len(group) < maxGroup {
+		select {
+		case req := <-l.appends:
+			group = append(group, req)
+			add(req)
+		case <-timer.C:
+			return group
+		case <-l.stopCommit:
+			return group
+		}
+	}
+	return group
+}
+
+func (l *Log) stopCommitter() {
+	select {
+	case <-l.stopCommit:
+		// already stopped
+		return
+	default:
+	}
+	close(l.stopCommit)
+	<-l.commitDone
+}
+
+// commit appends the group's records to the log. The records go to the active segment in as few writes as
+// the segment rolls allow, and each chunk written to a segment gets a single durability barrier.
+func (l *Log) commit(group []*appendRequest) {
+	l.mu.Lock()
+	pending := l.admit(group)
+	opened := l.beginTxns(pending)
+	batches := make([][]*api.Record, len(pending))
+	for i, req := range pending {
+		batches[i] = req.records
+	}
+	// pending[:acked] made it to the log, whatever went wrong afterwards
+	acked, err := 0, error(nil)
+	// the last segment the commit sealed, if any
+	var sealed *segment
+	for acked < len(pending) {
+		s := l.activeSegment
+		// the segment may have aged past MaxAge since the last append
+		if s.entries.Load() > 0 && s.IsMaxed() {
+			if err = l.roll(s); err != nil {
+				break
+			}
+			sealed = s
+			continue
+		}
+		size := s.store.size
+		var n int
+		if n, err = s.appendGroup(batches[acked:]); err != nil {
+			break
+		}
+		var batch *syncBatch
+		if batch, err = l.afterAppend(s, s.store.size-size); err != nil {
+			break
+		}
+		for _, req := range pending[acked : acked+n] {
+			req.off, req.batch = req.records[0].Offset, batch
+		}
+		acked += n
+		if s.IsMaxed() {
+			if err = l.roll(s); err != nil {
+				break
+			}
+			sealed = s
+		}
+	}
+	l.recordSequences(pending[:acked])
+	l.recordKeys(pending[:acked])
+	l.recordTxns(pending[:acked], opened)
+	if sealed != nil {
+		l.checkpointProducers(sealed)
+	}
+	l.mu.Unlock()
+	if acked > 0 {
+		l.notifyAppended()
+	}
+	for _, req := range pending[acked:] {
+		req.err = err
+	}
+	for _, req := range group {
+		if original := req.original; original != nil {
+			req.off, req.batch, req.err = original.off+req.delta, original.batch, original.err
+		}
+		close(req.done)
+	}
+}
+
+// admit goes over the group's appends in order and returns the ones to append. The rest are settled
+// without appending: idempotent producers' retries, and the appends out of sequence or whose expectations
+// don't hold, see producers.go and expect.go. It's called with l.mu held.
+func (l *Log) admit(group []*appendRequest) []*appendRequest {
+	pending := make([]*appendRequest, 0, len(group))
+	seqs := &sequences{}
+	next := l.activeSegment.nextOffset.Load()
+	for _, req := range group {
+		if !l.checkSequence(req, seqs) {
+			continue
+		}
+		if req.err = l.checkExpectation(req, pending, next); req.err != nil {
+			continue
+		}
+		l.acceptSequence(req, seqs)
+		pending = append(pending, req)
+		next += uint64(len(req.records))
+	}
+	return pending
+}
+
+// roll seals the active segment and starts a new one. It's called with l.mu held.
+func (l *Log) roll(s *segment) error {
+	if err := l.beforeRoll(s); err != nil {
+		return err
+	}
+	return l.newSegment(s.nextOffset.Load())
+}
@@ -1,8 +1,15 @@
+/*
+	The index file is memory-mapped with room to spare and grows by IndexGrowBytes whenever it fills up,
+	by extending the file and mapping it again. Readers go through the mapping without locking, so a mapping
+	the index has grown out of is only unmapped once the index is closed.
+*/
+
 package log
 
 import (
 	"io"
 	"os"
+	"sync/atomic"
 
 	"github.com/tysonmote/gommap"
 )
@@ -14,33 +21,57 @@ var (
 )
 
 type index struct {
-	file *os.File
-	mmap gommap.MMap
-	size uint64
+	file    *os.File
+	mmap    atomic.Pointer[gommap.MMap]
+	retired []gommap.MMap
+	size    uint64
+	grow    uint64
 }
 
 func newIndex(f *os.File, c Config) (*index, error) {
-	idx := &index{file: f}
+	idx := &index{file: f, grow: max(c.Segment.IndexGrowBytes, endWidth)}
 	fi, err := os.Stat(f.Name())
 	if err != nil {
 		return nil, err
 	}
 	idx.size = uint64(fi.Size())
-	if err = os.Truncate(f.Name(), int64(c.Segment.MaxIndexBytes)); err != nil {
+	if err = idx.remap(idx.size + idx.grow); err != nil {
 		return nil, err
 	}
-	if idx.mmap, err = gommap.Map(
-		idx.file.Fd(),
+	return idx, nil
+}
+
+// remap extends the index file to size and maps it again.
+func (i *index) remap(size uint64) error {
+	if err := os.Truncate(i.file.Name(), int64(size)); err != nil {
+		return err
+	}
+	mmap, err := gommap.Map(
+		i.file.Fd(),
 		gommap.PROT_READ|gommap.PROT_WRITE,
 		gommap.MAP_SHARED,
-	); err != nil {
-		return nil, err
+	)
+	if err != nil {
+		return err
 	}
-	return idx, nil
+	if old := i.mmap.Swap(&mmap); old != nil {
+		i.retired = append(i.retired, *old)
+	}
+	return nil
+}
+
+// mapped returns the current mapping.
+func (i *index) mapped() gommap.MMap {
+	return *i.mmap.Load()
+}
+
+// Sync commits the memory-mapped entries to stable storage.
+func (i *index) Sync() error {
+	return i.mapped().Sync(gommap.MS_SYNC)
 }
 
 func (i *index) Close() error {
-	if err := i.mmap.Sync(gommap.MS_SYNC); err != nil {
+	if err := i.Sync(); err != nil {
 		return err
 	}
 	if err := i.file.Sync(); err != nil {
@@ -49,6 +80,11 @@ func (i *index) Close() error {
 	if err := i.file.Truncate(int64(i.size)); err != nil {
 		return err
 	}
+	for _, mmap := range append(i.retired, i.mapped()) {
+		if err := mmap.UnsafeUnmap(); err != nil {
+			return err
+		}
+	}
 	return i.file.Close()
 }
 
@@ -65,21 +101,42 @@ func (i *index) Read(in int64) (out uint32, pos uint64, err error) {
 	if i.size < pos+endWidth {
 		return 0, 0, io.EOF
 	}
-	out = enc.Uint32(i.mmap[pos : pos+offWidth])
-	pos = enc.Uint64(i.mmap[pos+offWidth : pos+endWidth])
+	out, pos = i.entry(uint64(out))
 	return out, pos, nil
 }
 
+// entry reads the n-th entry without checking it against the index's size, for callers that already know
+// it's been written.
+func (i *index) entry(n uint64) (out uint32, pos uint64) {
+	mmap, start := i.mapped(), n*endWidth
+	out = enc.Uint32(mmap[start : start+offWidth])
+	pos = enc.Uint64(mmap[start+offWidth : start+endWidth])
+	return out, pos
+}
+
 func (i *index) Write(off uint32, pos uint64) error {
-	if uint64(len(i.mmap)) < i.size+endWidth {
-		return io.EOF
+	mmap := i.mapped()
+	if uint64(len(mmap)) < i.size+endWidth {
+		if err := i.remap(uint64(len(mmap)) + i.grow); err != nil {
+			return err
+		}
+		mmap = i.mapped()
 	}
-	enc.PutUint32(i.mmap[i.size:i.size+offWidth], off)
-	enc.PutUint64(i.mmap[i.size+offWidth:i.size+endWidth], pos)
+	enc.PutUint32(mmap[i.size:i.size+offWidth], off)
+	enc.PutUint64(mmap[i.size+offWidth:i.size+endWidth], pos)
 	i.size += uint64(endWidth)
 	return nil
 }
 
+// Truncate drops every entry from the n-th onwards and zeroes them so a later crash can't bring them back.
+func (i *index) Truncate(n uint64) {
+	mmap, size := i.mapped(), n*endWidth
+	if end := min(i.size, uint64(len(mmap))); size < end {
+		clear(mmap[size:end])
+	}
+	i.size = size
+}
+
 func (i *index) Name() string {
 	return i.file.Name()
 }
@@ -13,7 +13,7 @@ func TestIndex(t *testing.T) {
 	require.NoError(t, err)
 	defer os.Remove(f.Name())
 	c := Config{}
-	c.Segment.MaxIndexBytes = 1024
+	c.Segment.IndexGrowBytes = 1024
 	idx, err := newIndex(f, c)
 	require.NoError(t, err)
 	_, _, err = idx.Read(-1)
@@ -49,3 +49,31 @@ func TestIndex(t *testing.T) {
 	require.Equal(t, uint32(1), off)
 	require.Equal(t, entries[1].pos, pos)
 }
+
+func TestIndexGrow(t *testing.T) {
+	f, err := os.CreateTemp(os.TempDir(), "index_grow_test")
+	require.NoError(t, err)
+	defer os.Remove(f.Name())
+	c := Config{}
+	c.Segment.IndexGrowBytes = endWidth * 2
+	idx, err := newIndex(f, c)
+	require.NoError(t, err)
+	// a reader in the middle of reading the mapping the index grows out of
+	mmap := idx.mapped()
+	for i := uint32(0); i < 10; i++ {
+		require.NoError(t, idx.Write(i, uint64(i)*10))
+	}
+	require.Less(t, len(mmap), len(idx.mapped()))
+	require.Equal(t, uint32(1), enc.Uint32(mmap[endWidth:endWidth+offWidth]))
+	for i := uint64(0); i < 10; i++ {
+		off, pos := idx.entry(i)
+		require.Equal(t, uint32(i), off)
+		require.Equal(t, i*10, pos)
+	}
+
+	// closing truncates the file to the entries written
+	require.NoError(t, idx.Close())
+	fi, err := os.Stat(f.Name())
+	require.NoError(t, err)
+	require.Equal(t, int64(10*endWidth), fi.Size())
+}
@@ -0,0 +1,82 @@
+/*
+	An iterator reads decoded records in order from an offset on, going from one segment to the next by
+	offset rather than holding on to segments, so it carries on while segments roll, get compacted or are
+	deleted underneath it. Records truncated or deleted by retention before the iterator got to them are
+	skipped: it carries on from the oldest record left.
+	Next returns false once the iterator has caught up with the log or reached its limits. Calling it again
+	after more appends picks up where it stopped, until a limit's reached.
+*/
+
+package log
+
+import (
+	"github.com/sant470/distlogs/api/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+type Iterator struct {
+	// MaxRecords stops the iterator after that many records, no limit if left zero.
+	MaxRecords int
+	// MaxBytes stops the iterator before the record that would take the records' encoded size past it,
+	// no limit if left zero. The first record is always read, however big.
+	MaxBytes uint64
+
+	log     *Log
+	off     uint64
+	records int
+	bytes   uint64
+	record  *api.Record
+	err     error
+}
+
+// Iterator returns an iterator over the records from offset from on.
+func (l *Log) Iterator(from uint64) *Iterator {
+	return &Iterator{log: l, off: from}
+}
+
+// Next reads the next record, and reports whether there was one within the iterator's limits.
+func (it *Iterator) Next() bool {
+	if it.err != nil || (it.MaxRecords > 0 && it.records >= it.MaxRecords) {
+		return false
+	}
+	for {
+		record, err := it.log.Read(it.off)
+		if _, ok := err.(api.ErrOffsetOutOfRange); ok {
+			lowest, _ := it.log.LowestOffset()
+			if it.off >= lowest {
+				// caught up
+				return false
+			}
+			it.off = lowest
+			continue
+		}
+		if err != nil {
+			it.err = err
+			return false
+		}
+		size := uint64(proto.Size(record))
+		if it.MaxBytes > 0 && it.records > 0 && it.bytes+size > it.MaxBytes {
+			return false
+		}
+		it.record = record
+		it.records++
+		it.bytes += size
+		it.off = record.Offset + 1
+		return true
+	}
+}
+
+// Record returns the record Next read.
+func (it *Iterator) Record() *api.Record {
+	return it.record
+}
+
+// Offset returns the offset the iterator reads from next.
+func (it *Iterator) Offset() uint64 {
+	return it.off
+}
+
+// Err returns the error that stopped the iterator, if any.
+func (it *Iterator) Err() error {
+	return it.err
+}
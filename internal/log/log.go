@@ -1,35 +1,115 @@
 package log
 
 import (
+	"errors"
 	"io"
 	"os"
 	"path"
+	"slices"
 	"sort"
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/sant470/distlogs/api/v1"
+	"go.uber.org/zap"
 )
 
+/*
+	Only writers take the log's lock: the committer, and whatever changes the segment list. Readers never do.
+	The segment list is published through an atomic pointer and never modified in place, a segment publishes
+	its next offset once a record is fully written, and stores serve frames the OS already has without
+	locking, so reads and Reader run in parallel with appends.
+*/
+
 type Log struct {
-	mu            sync.RWMutex
+	mu            sync.Mutex
 	Dir           string
 	Config        Config
 	activeSegment *segment
-	segments      []*segment
+	segments      atomic.Pointer[[]*segment]
+	repairs       []Repair
+	logger        *zap.Logger
+	// durability bookkeeping, see durability.go
+	unsynced uint64
+	batch    *syncBatch
+	stopSync chan struct{}
+	syncDone chan struct{}
+	// group commit, see groupcommit.go
+	appends    chan *appendRequest
+	stopCommit chan struct{}
+	commitDone chan struct{}
+	// retention, see retention.go
+	stopRetain chan struct{}
+	retainDone chan struct{}
+	// compaction, see compaction.go. compactedTo and tombstonesDue are compactMu's.
+	compactMu     sync.Mutex
+	compactedTo   uint64
+	tombstonesDue int64
+	// rebuildMu is held while RebuildIndexes rewrites indexes, readers kept out of a segment wait on it
+	rebuildMu   sync.RWMutex
+	stopCompact chan struct{}
+	compactDone chan struct{}
+	// readers waiting for appends, see wait.go
+	waitMu   sync.Mutex
+	appended chan struct{}
+	// idempotent producers' latest sequence numbers, see producers.go
+	producers map[uint64]*producerState
+	// transactions, see transactions.go. openTxns and aborted are the committer's, readers go by
+	// firstOpen and abortedTxns.
+	openTxns    map[uint64]uint64
+	aborted     []abortedTxn
+	firstOpen   atomic.Uint64
+	abortedTxns atomic.Pointer[abortedIndex]
+	// the latest offset of each key, once an append expects one, see expect.go. keys is the committer's,
+	// loadKeysMu has the appends that find it do so one at a time.
+	keys       *keyIndex
+	keysLoaded atomic.Bool
+	loadKeysMu sync.Mutex
 }
 
 func NewLog(dir string, c Config) (*Log, error) {
 	if c.Segment.MaxStoreBytes == 0 {
 		c.Segment.MaxStoreBytes = 1024
 	}
-	if c.Segment.MaxIndexBytes == 0 {
-		c.Segment.MaxIndexBytes = 1024
+	if c.Segment.IndexGrowBytes == 0 {
+		c.Segment.IndexGrowBytes = 64 << 10
+	}
+	if c.Segment.TimeIndexIntervalBytes == 0 {
+		c.Segment.TimeIndexIntervalBytes = 4096
+	}
+	if c.Durability.Interval == 0 {
+		c.Durability.Interval = 10 * time.Millisecond
+	}
+	if c.Durability.Bytes == 0 {
+		c.Durability.Bytes = 1 << 20
+	}
+	if c.Retention.CheckInterval == 0 {
+		c.Retention.CheckInterval = time.Minute
+	}
+	if c.Compaction.TombstoneRetention == 0 {
+		c.Compaction.TombstoneRetention = 24 * time.Hour
+	}
+	if c.Compaction.Interval == 0 {
+		c.Compaction.Interval = time.Minute
+	}
+	if c.Compaction.MinDirtyRatio == 0 {
+		c.Compaction.MinDirtyRatio = 0.5
+	}
+	if c.Compression.MinBatchBytes == 0 {
+		c.Compression.MinBatchBytes = 1024
+	}
+	if c.Compression.Codec != nil {
+		if err := RegisterCodec(c.Compression.Codec); err != nil {
+			return nil, err
+		}
 	}
 	l := &Log{
 		Dir:    dir,
 		Config: c,
+		logger: zap.L().Named("log"),
 	}
 	return l, l.setup()
 }
@@ -39,19 +119,49 @@ func (l *Log) newSegment(off uint64) error {
 	if err != nil {
 		return err
 	}
-	l.segments = append(l.segments, s)
+	if s.repair.Repaired() {
+		l.logger.Warn(
+			"recovered segment",
+			zap.String("dir", l.Dir),
+			zap.Uint64("base_offset", s.repair.BaseOffset),
+			zap.Uint64("dropped_index_entries", s.repair.DroppedIndexEntries),
+			zap.Uint64("trimmed_index_bytes", s.repair.TrimmedIndexBytes),
+			zap.Uint64("rebuilt_index_entries", s.repair.RebuiltIndexEntries),
+			zap.Uint64("truncated_store_bytes", s.repair.TruncatedStoreBytes),
+		)
+		l.repairs = append(l.repairs, s.repair)
+	}
+	l.publish(append(slices.Clone(l.loadSegments()), s))
 	l.activeSegment = s
 	return nil
 }
 
+// loadSegments returns the published segment list, oldest first. Callers must not modify it.
+func (l *Log) loadSegments() []*segment {
+	if segments := l.segments.Load(); segments != nil {
+		return *segments
+	}
+	return nil
+}
+
+// publish replaces the segment list readers see. It's called with l.mu held.
+func (l *Log) publish(segments []*segment) {
+	l.segments.Store(&segments)
+}
+
 func (l *Log) setup() error {
+	// a compaction that was cut short leaves its half-written segments behind
+	if err := os.RemoveAll(path.Join(l.Dir, compactingDir)); err != nil {
+		return err
+	}
 	files, err := os.ReadDir(l.Dir)
 	if err != nil {
 		return err
 	}
 	var baseOffsets []uint64
 	for _, file := range files {
-		if path.Ext(file.Name()) == ".index" {
+		// the store is the source of truth for a segment, its index can be rebuilt from it
+		if path.Ext(file.Name()) != ".store" {
 			continue
 		}
 		offStr := strings.TrimSuffix(file.Name(), path.Ext(file.Name()))
@@ -59,61 +169,167 @@ func (l *Log) setup() error {
 		baseOffsets = append(baseOffsets, off)
 	}
 	sort.Slice(baseOffsets, func(i, j int) bool { return baseOffsets[i] < baseOffsets[j] })
+	// a reset log still has its closed segments published, for readers to find it closed until it's set up
+	stale := len(l.loadSegments())
 	for i := 0; i < len(baseOffsets); i++ {
 		if err = l.newSegment(baseOffsets[i]); err != nil {
 			return err
 		}
 	}
-	if l.segments == nil {
+	if len(l.loadSegments()) == stale {
 		if err = l.newSegment(l.Config.Segment.InitialOffset); err != nil {
 			return err
 		}
 	}
+	if stale > 0 {
+		l.publish(slices.Clone(l.loadSegments()[stale:]))
+	}
+	l.keys = nil
+	l.keysLoaded.Store(false)
+	if err = l.loadProducers(); err != nil {
+		return err
+	}
+	l.startSyncer()
+	l.startCommitter()
+	l.startRetention()
+	l.startCompactor()
 	return nil
 }
 
+// Append returns the record's offset once the configured durability guarantee is met. Concurrent appends
+// are committed together, see groupcommit.go.
 func (l *Log) Append(record *api.Record) (uint64, error) {
-	// should move the lock to the segment
-	l.mu.Lock()
-	defer l.mu.Unlock()
-	off, err := l.activeSegment.Append(record)
-	if err != nil {
-		return 0, err
-	}
-	if l.activeSegment.IsMaxed() {
-		err = l.newSegment(off + 1)
-	}
+	return l.submit([]*api.Record{record}, Expectation{})
+}
 
-	return off, err
+// AppendBatch appends the records atomically and returns the first one's offset: all of them become visible,
+// and survive a crash, or none do.
+func (l *Log) AppendBatch(records []*api.Record) (uint64, error) {
+	if len(records) == 0 {
+		return 0, ErrEmptyBatch
+	}
+	return l.submit(records, Expectation{})
 }
 
+// Read returns the record at off, or the next one after it if compaction removed it.
 func (l *Log) Read(off uint64) (*api.Record, error) {
-	l.mu.RLock()
-	defer l.mu.RUnlock()
-	var s *segment
-	for _, segment := range l.segments {
-		if segment.baseOffset <= off && off < segment.nextOffset {
-			s = segment
-			break
+	for {
+		record, err := l.read(off, l.loadSegments())
+		if err != errSegmentReleased {
+			return record, err
+		}
+		if !l.waitRebuild() {
+			return nil, ErrClosed
 		}
 	}
-	if s == nil || s.nextOffset <= off {
+}
+
+// errSegmentReleased tells Read a segment was deleted or compacted since it loaded the segment list.
+var errSegmentReleased = errors.New("segment released")
+
+func (l *Log) read(off uint64, segments []*segment) (*api.Record, error) {
+	if off < segments[0].baseOffset {
 		return nil, api.ErrOffsetOutOfRange{Offset: off}
 	}
-	return s.Read(off)
+	for _, s := range segments {
+		if s.nextOffset.Load() <= off {
+			continue
+		}
+		if !s.acquire() {
+			return nil, errSegmentReleased
+		}
+		record, err := s.Read(max(off, s.baseOffset))
+		l.releaseSegment(s)
+		// compaction may have removed the rest of the segment
+		if err == io.EOF {
+			continue
+		}
+		return record, err
+	}
+	return nil, api.ErrOffsetOutOfRange{Offset: off}
 }
 
-func (l *Log) Close() error {
+// OffsetForTime returns the first offset appended at or after t, or the next offset to be appended if
+// there's none. Records appended before the log stamped them count as appended at the Unix epoch.
+func (l *Log) OffsetForTime(t time.Time) (uint64, error) {
+	ts := t.UnixNano()
+	for {
+		off, err := l.offsetForTime(ts, l.loadSegments())
+		if err != errSegmentReleased {
+			return off, err
+		}
+		if !l.waitRebuild() {
+			return 0, ErrClosed
+		}
+	}
+}
+
+func (l *Log) offsetForTime(ts int64, segments []*segment) (uint64, error) {
+	for _, s := range segments {
+		if s.maxTimestamp.Load() < ts {
+			continue
+		}
+		if !s.acquire() {
+			return 0, errSegmentReleased
+		}
+		defer l.releaseSegment(s)
+		return s.offsetForTime(ts)
+	}
+	return segments[len(segments)-1].nextOffset.Load(), nil
+}
+
+// RebuildIndexes rebuilds every segment's index from its store, e.g. after restoring the stores from a backup.
+func (l *Log) RebuildIndexes() error {
+	// compaction holds on to segments while it reads them, and would wait for l.mu to swap them
+	l.compactMu.Lock()
+	defer l.compactMu.Unlock()
 	l.mu.Lock()
 	defer l.mu.Unlock()
-	for _, segment := range l.segments {
-		if err := segment.Close(); err != nil {
+	l.rebuildMu.Lock()
+	defer l.rebuildMu.Unlock()
+	for _, s := range l.loadSegments() {
+		// the index is rewritten in place, readers mustn't see it halfway
+		s.fence()
+		repair, err := s.rebuildIndex()
+		s.unfence()
+		if err != nil {
 			return err
 		}
+		l.logger.Info(
+			"rebuilt index",
+			zap.String("dir", l.Dir),
+			zap.Uint64("base_offset", repair.BaseOffset),
+			zap.Uint64("entries", repair.RebuiltIndexEntries),
+			zap.Uint64("truncated_store_bytes", repair.TruncatedStoreBytes),
+		)
 	}
 	return nil
 }
 
+// Repairs returns what recovery had to fix in the segments found on disk when the log was set up.
+func (l *Log) Repairs() []Repair {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.repairs
+}
+
+func (l *Log) Close() error {
+	l.stopCompactor()
+	l.stopRetention()
+	l.stopCommitter()
+	l.stopSyncer()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	// the segments are closed even without a snapshot, setup rebuilds the producers' table then
+	err := l.snapshotProducers()
+	for _, segment := range l.loadSegments() {
+		// readers still in the segment would touch its unmapped index, later ones get ErrClosed
+		segment.fence()
+		err = errors.Join(err, segment.Close())
+	}
+	return err
+}
+
 func (l *Log) Remove() error {
 	if err := l.Close(); err != nil {
 		return err
@@ -125,19 +341,19 @@ func (l *Log) Reset() error {
 	if err := l.Remove(); err != nil {
 		return err
 	}
+	if err := os.MkdirAll(l.Dir, 0755); err != nil {
+		return err
+	}
 	return l.setup()
 }
 
 func (l *Log) LowestOffset() (uint64, error) {
-	l.mu.Lock()
-	defer l.mu.Unlock()
-	return l.segments[0].baseOffset, nil
+	return l.loadSegments()[0].baseOffset, nil
 }
 
 func (l *Log) HighestOffset() (uint64, error) {
-	l.mu.Lock()
-	defer l.mu.Unlock()
-	off := l.segments[len(l.segments)-1].nextOffset
+	segments := l.loadSegments()
+	off := segments[len(segments)-1].nextOffset.Load()
 	if off == 0 {
 		return 0, nil
 	}
@@ -148,36 +364,45 @@ func (l *Log) Truncate(lowest uint64) error {
 	l.mu.Lock()
 	defer l.mu.Unlock()
 	var segments []*segment
-	for _, s := range l.segments {
-		if s.nextOffset <= lowest+1 {
-			if err := s.Remove(); err != nil {
+	for _, s := range l.loadSegments() {
+		if s.nextOffset.Load() <= lowest+1 {
+			if err := s.release(); err != nil {
 				return err
 			}
 			continue
 		}
 		segments = append(segments, s)
 	}
-	l.segments = segments
+	l.publish(segments)
+	if len(segments) > 0 && l.pruneAbortedTxns(segments[0].baseOffset) {
+		l.publishTxns()
+	}
 	return nil
 }
 
 type originReader struct {
-	*store
-	off int64
+	log     *Log
+	segment *segment
+	off     int64
 }
 
 func (o *originReader) Read(p []byte) (int, error) {
-	n, err := o.ReadAt(p, o.off)
+	if !o.segment.acquire() {
+		if !o.log.waitRebuild() || !o.segment.acquire() {
+			return 0, api.ErrOffsetOutOfRange{Offset: o.segment.baseOffset}
+		}
+	}
+	defer o.log.releaseSegment(o.segment)
+	n, err := o.segment.store.ReadAt(p, o.off)
 	o.off += int64(n)
 	return n, err
 }
 
 func (l *Log) Reader() io.Reader {
-	l.mu.RLock()
-	defer l.mu.RUnlock()
-	readers := make([]io.Reader, len(l.segments))
-	for i, segment := range l.segments {
-		readers[i] = &originReader{segment.store, 0}
+	segments := l.loadSegments()
+	readers := make([]io.Reader, len(segments))
+	for i, segment := range segments {
+		readers[i] = &originReader{l, segment, 0}
 	}
 	return io.MultiReader(readers...)
 }
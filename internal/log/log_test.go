@@ -1,9 +1,16 @@
 package log
 
 import (
+	"context"
+	"encoding/json"
+	"fmt"
 	"io"
 	"os"
+	"path"
+	"runtime"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/sant470/distlogs/api/v1"
 	"github.com/stretchr/testify/require"
@@ -17,13 +24,18 @@ func TestLog(t *testing.T) {
 		"init with existing segment":        testInitExisting,
 		"reader":                            testReader,
 		"truncate":                          testTruncate,
+		"recover after crash":               testRecoverAfterCrash,
+		"rebuild indexes":                   testRebuildIndexes,
+		"reset":                             testReset,
+		"offset for time":                   testOffsetForTime,
 	} {
 		t.Run(scenario, func(t *testing.T) {
 			dir, err := os.MkdirTemp("", "store-test")
 			require.NoError(t, err)
 			defer os.RemoveAll(dir)
 			c := Config{}
-			c.Segment.MaxIndexBytes = 64
+			// five records fill a segment
+			c.Segment.MaxStoreBytes = 150
 			log, err := NewLog(dir, c)
 			require.NoError(t, err)
 			fn(t, log)
@@ -84,7 +96,7 @@ func testReader(t *testing.T, log *Log) {
 	barr, err := io.ReadAll(reader)
 	require.NoError(t, err)
 	read := api.Record{}
-	err = proto.Unmarshal(barr[lenWidth:], &read)
+	err = proto.Unmarshal(barr[lenWidth+crcWidth:], &read)
 	require.NoError(t, err)
 	require.Equal(t, append.Value, read.Value)
 }
@@ -100,3 +112,1286 @@ func testTruncate(t *testing.T, log *Log) {
 	_, err = log.Read(0)
 	require.NoError(t, err) // test case are failing, need to check it in details
 }
+
+func testRecoverAfterCrash(t *testing.T, log *Log) {
+	append := api.Record{Value: []byte("hello world")}
+	for i := 0; i < 3; i++ {
+		_, err := log.Append(&append)
+		require.NoError(t, err)
+	}
+	require.Empty(t, log.Repairs())
+	crash(t, log.activeSegment)
+
+	nl, err := NewLog(log.Dir, log.Config)
+	require.NoError(t, err)
+	require.Len(t, nl.Repairs(), 1)
+	off, err := nl.HighestOffset()
+	require.NoError(t, err)
+	require.Equal(t, uint64(2), off)
+	off, err = nl.Append(&append)
+	require.NoError(t, err)
+	require.Equal(t, uint64(3), off)
+}
+
+func testRebuildIndexes(t *testing.T, log *Log) {
+	append := api.Record{Value: []byte("hello world")}
+	for i := 0; i < 4; i++ {
+		_, err := log.Append(&append)
+		require.NoError(t, err)
+	}
+	// an index restored from an older backup is shorter than its store
+	log.loadSegments()[0].index.Truncate(1)
+	require.NoError(t, log.RebuildIndexes())
+	for off := uint64(0); off < 4; off++ {
+		read, err := log.Read(off)
+		require.NoError(t, err)
+		require.Equal(t, off, read.Offset)
+	}
+	require.Equal(t, uint64(4), log.activeSegment.nextOffset.Load())
+}
+
+func TestLogRebuildIndexesConcurrentReads(t *testing.T) {
+	dir, err := os.MkdirTemp("", "log-rebuild-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	c := Config{}
+	c.Segment.MaxStoreBytes = 1 << 20
+	log, err := NewLog(dir, c)
+	require.NoError(t, err)
+	defer log.Close()
+	appendRecords(t, log, 2000)
+
+	// readers never see an index halfway through its rebuild
+	done := make(chan struct{})
+	errs := make(chan error, 4)
+	for i := 0; i < 4; i++ {
+		go func() {
+			for {
+				for off := uint64(0); off < 2000; off += 7 {
+					select {
+					case <-done:
+						errs <- nil
+						return
+					default:
+					}
+					record, err := log.Read(off)
+					if err == nil && record.Offset != off {
+						err = fmt.Errorf("read offset %d, got %d", off, record.Offset)
+					}
+					if err != nil {
+						errs <- err
+						return
+					}
+				}
+			}
+		}()
+	}
+	for i := 0; i < 10; i++ {
+		require.NoError(t, log.RebuildIndexes())
+	}
+	close(done)
+	for i := 0; i < 4; i++ {
+		require.NoError(t, <-errs)
+	}
+}
+
+func testReset(t *testing.T, log *Log) {
+	appendRecords(t, log, 7)
+	require.NoError(t, log.Reset())
+	require.Len(t, log.loadSegments(), 1)
+	_, err := log.Read(0)
+	require.Equal(t, api.ErrOffsetOutOfRange{Offset: 0}, err)
+	off, err := log.Append(&api.Record{Value: []byte("hello world")})
+	require.NoError(t, err)
+	require.Equal(t, uint64(0), off)
+	require.NoError(t, log.Close())
+}
+
+func TestLogCloseConcurrentReads(t *testing.T) {
+	dir, err := os.MkdirTemp("", "log-close-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	c := Config{}
+	c.Segment.MaxStoreBytes = 150
+	log, err := NewLog(dir, c)
+	require.NoError(t, err)
+	appendRecords(t, log, 20)
+
+	// readers caught by Close give up with ErrClosed instead of reading unmapped indexes
+	errs := make(chan error, 4)
+	for i := 0; i < 4; i++ {
+		go func() {
+			for off := uint64(0); ; off = (off + 1) % 20 {
+				if _, err := log.Read(off); err != nil {
+					errs <- err
+					return
+				}
+			}
+		}()
+	}
+	time.Sleep(10 * time.Millisecond)
+	require.NoError(t, log.Close())
+	for i := 0; i < 4; i++ {
+		require.Equal(t, ErrClosed, <-errs)
+	}
+}
+
+func testOffsetForTime(t *testing.T, log *Log) {
+	append := api.Record{Value: []byte("hello world")}
+	start := time.Now()
+	for i := 0; i < 7; i++ {
+		_, err := log.Append(&append)
+		require.NoError(t, err)
+	}
+	time.Sleep(time.Millisecond)
+	mid := time.Now()
+	for i := 0; i < 3; i++ {
+		_, err := log.Append(&append)
+		require.NoError(t, err)
+	}
+	// the records span segments, the one at mid is in the second
+	require.Len(t, log.loadSegments(), 3)
+
+	check := func(l *Log) {
+		off, err := l.OffsetForTime(start)
+		require.NoError(t, err)
+		require.Equal(t, uint64(0), off)
+		off, err = l.OffsetForTime(mid)
+		require.NoError(t, err)
+		require.Equal(t, uint64(7), off)
+		off, err = l.OffsetForTime(time.Now())
+		require.NoError(t, err)
+		require.Equal(t, uint64(10), off)
+	}
+	check(log)
+	// the time indexes are read back from disk
+	require.NoError(t, log.Close())
+	nl, err := NewLog(log.Dir, log.Config)
+	require.NoError(t, err)
+	check(nl)
+}
+
+func TestLogAppendBatch(t *testing.T) {
+	batch := func(n int) []*api.Record {
+		var records []*api.Record
+		for i := 0; i < n; i++ {
+			records = append(records, &api.Record{Value: []byte(fmt.Sprintf("record %d", i))})
+		}
+		return records
+	}
+	reopen := func(t *testing.T, log *Log) *Log {
+		crash(t, log.activeSegment)
+		nl, err := NewLog(log.Dir, log.Config)
+		require.NoError(t, err)
+		return nl
+	}
+	// five records fill a segment, each scenario returns the log it leaves open
+	for scenario, fn := range map[string]func(t *testing.T, log *Log) *Log{
+		"past MaxStoreBytes": func(t *testing.T, log *Log) *Log {
+			appendRecords(t, log, 4)
+			off, err := log.AppendBatch(batch(3))
+			require.NoError(t, err)
+			require.Equal(t, uint64(4), off)
+			// the batch went whole to the segment, which rolled after it
+			segments := log.loadSegments()
+			require.Len(t, segments, 2)
+			require.Equal(t, uint64(7), segments[1].baseOffset)
+			for off := uint64(4); off < 7; off++ {
+				read, err := log.Read(off)
+				require.NoError(t, err)
+				require.Equal(t, fmt.Sprintf("record %d", off-4), string(read.Value))
+			}
+			return log
+		},
+		"empty": func(t *testing.T, log *Log) *Log {
+			_, err := log.AppendBatch(nil)
+			require.Equal(t, ErrEmptyBatch, err)
+			return log
+		},
+		"crash before the index entries": func(t *testing.T, log *Log) *Log {
+			_, err := log.AppendBatch(batch(3))
+			require.NoError(t, err)
+			log.activeSegment.index.Truncate(0)
+			log = reopen(t, log)
+			off, err := log.HighestOffset()
+			require.NoError(t, err)
+			require.Equal(t, uint64(2), off)
+			return log
+		},
+		"crash in the middle of the index entries": func(t *testing.T, log *Log) *Log {
+			_, err := log.AppendBatch(batch(4))
+			require.NoError(t, err)
+			log.activeSegment.index.Truncate(1)
+			log = reopen(t, log)
+			require.Equal(t, uint64(3), log.Repairs()[0].RebuiltIndexEntries)
+			for off := uint64(0); off < 4; off++ {
+				read, err := log.Read(off)
+				require.NoError(t, err)
+				require.Equal(t, fmt.Sprintf("record %d", off), string(read.Value))
+			}
+			return log
+		},
+		"crash in the middle of the store write": func(t *testing.T, log *Log) *Log {
+			appendRecords(t, log, 1)
+			_, err := log.AppendBatch(batch(3))
+			require.NoError(t, err)
+			s := log.activeSegment
+			size := s.store.size
+			crash(t, s)
+			require.NoError(t, os.Truncate(s.store.Name(), int64(size-3)))
+			nl, err := NewLog(log.Dir, log.Config)
+			require.NoError(t, err)
+			off, err := nl.HighestOffset()
+			require.NoError(t, err)
+			require.Equal(t, uint64(0), off)
+			_, err = nl.Read(1)
+			require.Equal(t, api.ErrOffsetOutOfRange{Offset: 1}, err)
+			off, err = nl.AppendBatch(batch(2))
+			require.NoError(t, err)
+			require.Equal(t, uint64(1), off)
+			return nl
+		},
+		"readers see all of a batch or none": func(t *testing.T, log *Log) *Log {
+			errs := make(chan error, 1)
+			go func() {
+				for i := 0; i < 20; i++ {
+					if _, err := log.AppendBatch(batch(3)); err != nil {
+						errs <- err
+						return
+					}
+				}
+				errs <- nil
+			}()
+			for {
+				select {
+				case err := <-errs:
+					require.NoError(t, err)
+					return log
+				default:
+				}
+				off, err := log.HighestOffset()
+				require.NoError(t, err)
+				require.True(t, off == 0 || (off+1)%3 == 0, "saw offset %d", off)
+				runtime.Gosched()
+			}
+		},
+	} {
+		t.Run(scenario, func(t *testing.T) {
+			dir, err := os.MkdirTemp("", "append-batch-test")
+			require.NoError(t, err)
+			defer os.RemoveAll(dir)
+			c := Config{}
+			c.Segment.MaxStoreBytes = 150
+			log, err := NewLog(dir, c)
+			require.NoError(t, err)
+			require.NoError(t, fn(t, log).Close())
+		})
+	}
+}
+
+func TestLogDurability(t *testing.T) {
+	for scenario, mode := range map[string]SyncMode{
+		"os":       SyncOS,
+		"always":   SyncAlways,
+		"interval": SyncInterval,
+		"bytes":    SyncBytes,
+	} {
+		t.Run(scenario, func(t *testing.T) {
+			dir, err := os.MkdirTemp("", "log-durability-test")
+			require.NoError(t, err)
+			defer os.RemoveAll(dir)
+			c := Config{}
+			c.Segment.MaxStoreBytes = 128
+			c.Durability.Mode = mode
+			c.Durability.Interval = time.Millisecond
+			c.Durability.Bytes = 64
+			log, err := NewLog(dir, c)
+			require.NoError(t, err)
+
+			produce := func() error {
+				for j := 0; j < 5; j++ {
+					off, err := log.Append(&api.Record{Value: []byte("hello world")})
+					if err != nil {
+						return err
+					}
+					// an acknowledged record has at least been handed to the OS
+					read, err := readFromDisk(log, off)
+					if err != nil {
+						return err
+					}
+					if string(read.Value) != "hello world" {
+						return fmt.Errorf("read %q from disk at offset %d", read.Value, off)
+					}
+					// and the modes that sync before acknowledging synced it
+					if (mode == SyncAlways || mode == SyncInterval) && !synced(log, off) {
+						return fmt.Errorf("record at offset %d acknowledged before it was synced", off)
+					}
+				}
+				return nil
+			}
+			errs := make(chan error, 4)
+			for i := 0; i < cap(errs); i++ {
+				go func() { errs <- produce() }()
+			}
+			for i := 0; i < cap(errs); i++ {
+				require.NoError(t, <-errs)
+			}
+			require.Greater(t, len(log.loadSegments()), 1)
+			var syncs uint64
+			for _, s := range log.loadSegments() {
+				syncs += s.store.syncs.Load()
+			}
+			switch mode {
+			case SyncOS:
+				require.Zero(t, syncs)
+			case SyncBytes:
+				// the log syncs once Bytes have been appended since the last sync
+				require.NotZero(t, syncs)
+				s := log.activeSegment
+				require.Less(t, s.store.size-s.store.synced.Load(), c.Durability.Bytes)
+			}
+			require.NoError(t, log.Close())
+		})
+	}
+}
+
+// synced reports whether the record at off has been fsynced.
+func synced(log *Log, off uint64) bool {
+	for _, s := range log.loadSegments() {
+		if off >= s.baseOffset && off < s.nextOffset.Load() {
+			_, pos := s.index.entry(off - s.baseOffset)
+			return pos < s.store.synced.Load()
+		}
+	}
+	return false
+}
+
+// readFromDisk reads the record at off straight from the store's file, bypassing its write buffer.
+func readFromDisk(log *Log, off uint64) (*api.Record, error) {
+	for _, s := range log.loadSegments() {
+		if off < s.baseOffset || s.nextOffset.Load() <= off {
+			continue
+		}
+		_, pos := s.index.entry(off - s.baseOffset)
+		f, err := os.Open(s.store.Name())
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		b, err := io.ReadAll(f)
+		if err != nil {
+			return nil, err
+		}
+		hdr := uint64(lenWidth + crcWidth)
+		size := enc.Uint64(b[pos:]) & frameLenMask
+		read := &api.Record{}
+		return read, proto.Unmarshal(b[pos+hdr:pos+hdr+size], read)
+	}
+	return nil, api.ErrOffsetOutOfRange{Offset: off}
+}
+
+func TestLogRetention(t *testing.T) {
+	for scenario, tc := range map[string]struct {
+		retention func(c *Config, segmentBytes uint64)
+		lowest    uint64
+	}{
+		"young and small segments are kept": {
+			retention: func(c *Config, segmentBytes uint64) {
+				c.Retention.MaxAge = time.Hour
+				c.Retention.MaxBytes = 3 * segmentBytes
+			},
+			lowest: 0,
+		},
+		"max bytes": {
+			retention: func(c *Config, segmentBytes uint64) {
+				c.Retention.MaxBytes = 2 * segmentBytes
+			},
+			lowest: 5,
+		},
+		"max age keeps the active segment": {
+			retention: func(c *Config, _ uint64) {
+				c.Retention.MaxAge = time.Nanosecond
+			},
+			lowest: 15,
+		},
+		"min segments": {
+			retention: func(c *Config, _ uint64) {
+				c.Retention.MaxAge = time.Nanosecond
+				c.Retention.MinSegments = 3
+			},
+			lowest: 5,
+		},
+	} {
+		t.Run(scenario, func(t *testing.T) {
+			dir, err := os.MkdirTemp("", "retention-test")
+			require.NoError(t, err)
+			defer os.RemoveAll(dir)
+			c := Config{}
+			c.Segment.MaxStoreBytes = 150
+			log, err := NewLog(dir, c)
+			require.NoError(t, err)
+			defer log.Close()
+			appendRecords(t, log, 15)
+			// three full segments of five records and an empty active one
+			require.Len(t, log.loadSegments(), 4)
+
+			// the first segment is a little smaller, its first record's offset is zero and so isn't encoded
+			tc.retention(&log.Config, log.loadSegments()[1].store.size)
+			require.NoError(t, log.EnforceRetention())
+			lowest, err := log.LowestOffset()
+			require.NoError(t, err)
+			require.Equal(t, tc.lowest, lowest)
+			if lowest < 15 {
+				_, err = log.Read(lowest)
+				require.NoError(t, err)
+			}
+			if lowest > 0 {
+				_, err = log.Read(lowest - 1)
+				require.Equal(t, api.ErrOffsetOutOfRange{Offset: lowest - 1}, err)
+			}
+		})
+	}
+}
+
+func TestLogRetentionConcurrentReader(t *testing.T) {
+	dir, err := os.MkdirTemp("", "retention-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	c := Config{}
+	c.Segment.MaxStoreBytes = 150
+	c.Retention.MaxAge = time.Nanosecond
+	c.Retention.CheckInterval = time.Millisecond
+	log, err := NewLog(dir, c)
+	require.NoError(t, err)
+	defer log.Close()
+
+	// a reader that picked up the oldest segment before it got deleted
+	oldest := log.loadSegments()[0]
+	require.True(t, oldest.acquire())
+	appendRecords(t, log, 5)
+	require.Eventually(t, func() bool {
+		lowest, err := log.LowestOffset()
+		return err == nil && lowest == 5
+	}, time.Second, time.Millisecond)
+
+	_, err = oldest.Read(0)
+	require.NoError(t, err)
+	_, err = os.Stat(oldest.store.Name())
+	require.NoError(t, err)
+
+	require.NoError(t, oldest.release())
+	_, err = os.Stat(oldest.store.Name())
+	require.True(t, os.IsNotExist(err))
+}
+
+func TestLogCompaction(t *testing.T) {
+	dir, err := os.MkdirTemp("", "compaction-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	c := Config{}
+	// five of the records below fill a segment
+	c.Segment.MaxStoreBytes = 130
+	c.Compaction.TombstoneRetention = time.Hour
+	log, err := NewLog(dir, c)
+	require.NoError(t, err)
+
+	// keys cycle through a, b and c, offset 7 has no key and offset 13 deletes b
+	for i := 0; i < 15; i++ {
+		record := &api.Record{Value: []byte(fmt.Sprint(i))}
+		if i != 7 {
+			record.Key = []byte{"abc"[i%3]}
+		}
+		if i == 13 {
+			record.Value = nil
+		}
+		_, err := log.Append(record)
+		require.NoError(t, err)
+	}
+	// a reader that's still on the first segment when it's compacted
+	oldest := log.loadSegments()[0]
+	require.True(t, oldest.acquire())
+	require.NoError(t, log.Compact())
+	read, err := oldest.Read(0)
+	require.NoError(t, err)
+	require.Equal(t, uint64(0), read.Offset)
+	require.NoError(t, oldest.release())
+
+	check := func(log *Log, reads map[uint64]uint64) {
+		for off, want := range reads {
+			read, err := log.Read(off)
+			require.NoError(t, err)
+			require.Equal(t, want, read.Offset)
+			if want == 13 {
+				require.Empty(t, read.Value)
+			} else {
+				require.Equal(t, fmt.Sprint(want), string(read.Value))
+			}
+		}
+	}
+	check(log, map[uint64]uint64{0: 7, 7: 7, 8: 12, 13: 13, 14: 14})
+
+	// the compacted segments are read back with their gaps, and rebuilt with them too
+	require.NoError(t, log.Close())
+	log, err = NewLog(dir, log.Config)
+	require.NoError(t, err)
+	require.Empty(t, log.Repairs())
+	check(log, map[uint64]uint64{0: 7, 8: 12, 13: 13})
+	require.NoError(t, log.RebuildIndexes())
+	check(log, map[uint64]uint64{0: 7, 8: 12, 13: 13})
+
+	// the tombstone goes once it's past its retention
+	log.Config.Compaction.TombstoneRetention = time.Nanosecond
+	require.NoError(t, log.Compact())
+	check(log, map[uint64]uint64{0: 7, 8: 12, 13: 14})
+	off, err := log.LowestOffset()
+	require.NoError(t, err)
+	require.Equal(t, uint64(0), off)
+	off, err = log.Append(&api.Record{Value: []byte("15")})
+	require.NoError(t, err)
+	require.Equal(t, uint64(15), off)
+	require.NoError(t, log.Close())
+}
+
+func TestLogCompactionDirtyRatio(t *testing.T) {
+	dir, err := os.MkdirTemp("", "compaction-dirty-ratio-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	c := Config{}
+	c.Segment.MaxStoreBytes = 150
+	c.Compaction.MinDirtyRatio = 0.5
+	log, err := NewLog(dir, c)
+	require.NoError(t, err)
+	defer log.Close()
+
+	_, err = log.Append(&api.Record{Key: []byte("a"), Value: []byte("stale")})
+	require.NoError(t, err)
+	appendRecords(t, log, 19)
+	require.NoError(t, log.Compact())
+	sealed := len(log.loadSegments()) - 1
+	require.Greater(t, sealed, 3)
+
+	// a segment's worth of records isn't enough to compact the log again
+	next := log.activeSegment.nextOffset.Load()
+	_, err = log.Append(&api.Record{Key: []byte("a"), Value: []byte("latest")})
+	require.NoError(t, err)
+	for len(log.loadSegments())-1 == sealed {
+		appendRecords(t, log, 1)
+	}
+	require.NoError(t, log.Compact())
+	read, err := log.Read(0)
+	require.NoError(t, err)
+	require.Equal(t, uint64(0), read.Offset)
+
+	// as many as were compacted already are
+	for len(log.loadSegments())-1 < 2*sealed+1 {
+		appendRecords(t, log, 1)
+	}
+	require.NoError(t, log.Compact())
+	read, err = log.Read(0)
+	require.NoError(t, err)
+	require.Equal(t, uint64(1), read.Offset)
+	read, err = log.Read(next)
+	require.NoError(t, err)
+	require.Equal(t, "latest", string(read.Value))
+}
+
+func TestLogCompression(t *testing.T) {
+	dir, err := os.MkdirTemp("", "compression-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	c := Config{}
+	c.Segment.MaxStoreBytes = 1 << 20
+	c.Compression.MinBatchBytes = 512
+	c.Compression.Linger = 50 * time.Millisecond
+
+	// the log switches from no compression to gzip to a codec of its own, and reads back all three
+	var want []string
+	var sizes []uint64
+	for i, codec := range []Codec{nil, Gzip, identityCodec{}} {
+		c.Compression.Codec = codec
+		log, err := NewLog(dir, c)
+		require.NoError(t, err)
+		size := log.activeSegment.store.size
+		errs := make(chan error, 50)
+		for j := 0; j < cap(errs); j++ {
+			value := fmt.Sprintf(`{"codec": %d, "n": %d}`, i, j)
+			want = append(want, value)
+			go func() {
+				_, err := log.Append(&api.Record{Value: []byte(value)})
+				errs <- err
+			}()
+		}
+		for j := 0; j < cap(errs); j++ {
+			require.NoError(t, <-errs)
+		}
+		sizes = append(sizes, log.activeSegment.store.size-size)
+		if codec != nil {
+			// the appends lingered for one another, so the batches hold many records, all but the last
+			// few that didn't make up MinBatchBytes
+			require.Less(t, frames(log, uint64(i*50), uint64(i*50+50)), 25)
+		}
+		require.Empty(t, log.Repairs())
+		require.NoError(t, log.Close())
+	}
+	require.Less(t, sizes[1], sizes[0]/2)
+
+	log, err := NewLog(dir, c)
+	require.NoError(t, err)
+	defer log.Close()
+	var got []string
+	for off := uint64(0); off < uint64(len(want)); off++ {
+		record, err := log.Read(off)
+		require.NoError(t, err)
+		got = append(got, string(record.Value))
+	}
+	require.ElementsMatch(t, want, got)
+}
+
+func TestLogCompressionMinBatchBytes(t *testing.T) {
+	// a lone producer's appends are each committed on their own, too small to be worth compressing
+	var sizes []uint64
+	for _, codec := range []Codec{nil, Gzip} {
+		dir, err := os.MkdirTemp("", "compression-min-batch-test")
+		require.NoError(t, err)
+		defer os.RemoveAll(dir)
+		c := Config{}
+		c.Segment.MaxStoreBytes = 1 << 20
+		c.Compression.Codec = codec
+		log, err := NewLog(dir, c)
+		require.NoError(t, err)
+		for i := 0; i < 50; i++ {
+			_, err := log.Append(&api.Record{Value: []byte(fmt.Sprintf(`{"n": %d}`, i))})
+			require.NoError(t, err)
+		}
+		require.Equal(t, 50, frames(log, 0, 50))
+		sizes = append(sizes, log.activeSegment.store.size)
+		require.NoError(t, log.Close())
+	}
+	require.Equal(t, sizes[0], sizes[1])
+}
+
+// frames returns how many frames the records from off to end are in, going by the index, which has an
+// entry for each record.
+func frames(log *Log, off, end uint64) int {
+	positions := make(map[[2]uint64]bool)
+	for ; off < end; off++ {
+		for _, s := range log.loadSegments() {
+			if off >= s.baseOffset && off < s.nextOffset.Load() {
+				_, pos := s.index.entry(off - s.baseOffset)
+				positions[[2]uint64{s.baseOffset, pos}] = true
+			}
+		}
+	}
+	return len(positions)
+}
+
+type identityCodec struct{}
+
+func (identityCodec) ID() byte                            { return 200 }
+func (identityCodec) Name() string                        { return "identity" }
+func (identityCodec) Compress(p []byte) ([]byte, error)   { return p, nil }
+func (identityCodec) Decompress(p []byte) ([]byte, error) { return p, nil }
+
+func appendRecords(t *testing.T, log *Log, n int) {
+	t.Helper()
+	for i := 0; i < n; i++ {
+		_, err := log.Append(&api.Record{Value: []byte("hello world")})
+		require.NoError(t, err)
+	}
+}
+
+func TestLogIterator(t *testing.T) {
+	// iterate reads what's left of the iterator and returns the offsets it read
+	iterate := func(t *testing.T, it *Iterator) []uint64 {
+		t.Helper()
+		var offs []uint64
+		for it.Next() {
+			require.Equal(t, "hello world", string(it.Record().Value))
+			offs = append(offs, it.Record().Offset)
+		}
+		require.NoError(t, it.Err())
+		return offs
+	}
+
+	for scenario, fn := range map[string]func(t *testing.T, log *Log){
+		"across segments": func(t *testing.T, log *Log) {
+			require.Equal(t, []uint64{3, 4, 5, 6, 7, 8, 9, 10, 11}, iterate(t, log.Iterator(3)))
+		},
+		"max records": func(t *testing.T, log *Log) {
+			it := log.Iterator(0)
+			it.MaxRecords = 4
+			require.Equal(t, []uint64{0, 1, 2, 3}, iterate(t, it))
+		},
+		"max bytes": func(t *testing.T, log *Log) {
+			record, err := log.Read(1)
+			require.NoError(t, err)
+			it := log.Iterator(1)
+			it.MaxBytes = uint64(2 * proto.Size(record))
+			require.Equal(t, []uint64{1, 2}, iterate(t, it))
+			// the first record is read whatever its size
+			it = log.Iterator(1)
+			it.MaxBytes = 1
+			require.Equal(t, []uint64{1}, iterate(t, it))
+		},
+		"truncated underneath": func(t *testing.T, log *Log) {
+			it := log.Iterator(0)
+			it.MaxRecords = 2
+			require.Equal(t, []uint64{0, 1}, iterate(t, it))
+			require.NoError(t, log.Truncate(6))
+			it.MaxRecords = 4
+			require.Equal(t, []uint64{5, 6}, iterate(t, it))
+		},
+		"appended after catching up": func(t *testing.T, log *Log) {
+			it := log.Iterator(10)
+			require.Equal(t, []uint64{10, 11}, iterate(t, it))
+			// rolls a new segment
+			appendRecords(t, log, 4)
+			require.Equal(t, []uint64{12, 13, 14, 15}, iterate(t, it))
+			require.Equal(t, uint64(16), it.Offset())
+		},
+	} {
+		t.Run(scenario, func(t *testing.T) {
+			dir, err := os.MkdirTemp("", "log-iterator-test")
+			require.NoError(t, err)
+			defer os.RemoveAll(dir)
+			c := Config{}
+			// five records fill a segment
+			c.Segment.MaxStoreBytes = 150
+			log, err := NewLog(dir, c)
+			require.NoError(t, err)
+			defer log.Close()
+			appendRecords(t, log, 12)
+			fn(t, log)
+		})
+	}
+}
+
+func TestLogFetch(t *testing.T) {
+	dir, err := os.MkdirTemp("", "log-fetch-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	c := Config{}
+	// five records fill a segment
+	c.Segment.MaxStoreBytes = 150
+	log, err := NewLog(dir, c)
+	require.NoError(t, err)
+	defer log.Close()
+	appendRecords(t, log, 12)
+	require.NoError(t, log.Truncate(6))
+	low, high := log.Watermarks()
+	require.Equal(t, uint64(5), low)
+	require.Equal(t, uint64(12), high)
+
+	offsets := func(records []*api.Record) []uint64 {
+		var offs []uint64
+		for _, record := range records {
+			offs = append(offs, record.Offset)
+		}
+		return offs
+	}
+	records, err := log.Fetch(5, 0, 0)
+	require.NoError(t, err)
+	require.Equal(t, []uint64{5, 6, 7, 8, 9, 10, 11}, offsets(records))
+	records, err = log.Fetch(6, 3, 0)
+	require.NoError(t, err)
+	require.Equal(t, []uint64{6, 7, 8}, offsets(records))
+	records, err = log.Fetch(6, 0, uint64(2*proto.Size(records[0])))
+	require.NoError(t, err)
+	require.Equal(t, []uint64{6, 7}, offsets(records))
+	// caught up
+	records, err = log.Fetch(12, 0, 0)
+	require.NoError(t, err)
+	require.Empty(t, records)
+	for _, off := range []uint64{4, 13} {
+		_, err = log.Fetch(off, 0, 0)
+		require.Equal(t, api.ErrOffsetOutOfRange{Offset: off}, err)
+	}
+}
+
+func TestLogWaitForOffset(t *testing.T) {
+	dir, err := os.MkdirTemp("", "log-wait-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	log, err := NewLog(dir, Config{})
+	require.NoError(t, err)
+	appendRecords(t, log, 1)
+	// already appended
+	require.NoError(t, log.WaitForOffset(context.Background(), 0))
+
+	wait := func(ctx context.Context, off uint64) <-chan error {
+		errs := make(chan error, 1)
+		go func() { errs <- log.WaitForOffset(ctx, off) }()
+		return errs
+	}
+	errs := wait(context.Background(), 2)
+	// the first append isn't the one it's waiting for
+	appendRecords(t, log, 1)
+	select {
+	case err := <-errs:
+		t.Fatalf("woke up before the offset was appended: %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+	appendRecords(t, log, 1)
+	require.NoError(t, <-errs)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errs = wait(ctx, 3)
+	cancel()
+	require.Equal(t, context.Canceled, <-errs)
+
+	errs = wait(context.Background(), 3)
+	require.NoError(t, log.Close())
+	require.Equal(t, ErrClosed, <-errs)
+}
+
+func TestLogIdempotentProducer(t *testing.T) {
+	dir, err := os.MkdirTemp("", "log-producer-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	log, err := NewLog(dir, Config{})
+	require.NoError(t, err)
+
+	produce := func(seq uint32, values ...string) (uint64, error) {
+		var records []*api.Record
+		for _, value := range values {
+			records = append(records, &api.Record{Value: []byte(value), ProducerId: 7})
+		}
+		records[0].Sequence = seq
+		return log.AppendBatch(records)
+	}
+	for _, tc := range []struct {
+		seq    uint32
+		values []string
+		off    uint64
+	}{
+		{0, []string{"a"}, 0},
+		{1, []string{"b"}, 1},
+		// retries get their original offsets back
+		{0, []string{"a"}, 0},
+		{1, []string{"b"}, 1},
+		{2, []string{"c", "d", "e"}, 2},
+		{3, []string{"d"}, 3},
+	} {
+		off, err := produce(tc.seq, tc.values...)
+		require.NoError(t, err)
+		require.Equal(t, tc.off, off)
+	}
+	_, err = produce(7, "h")
+	require.Equal(t, api.ErrOutOfOrderSequence{ProducerID: 7, Sequence: 7, Expected: 5}, err)
+	_, err = log.Append(&api.Record{Value: []byte("x"), ProducerId: 8, Sequence: 1})
+	require.Equal(t, api.ErrOutOfOrderSequence{ProducerID: 8, Sequence: 1, Expected: 0}, err)
+	// the batch's records carry on from its first one's sequence number
+	record, err := log.Read(4)
+	require.NoError(t, err)
+	require.Equal(t, uint32(4), record.Sequence)
+	off, err := log.Append(&api.Record{Value: []byte("y")})
+	require.NoError(t, err)
+	require.Equal(t, uint64(5), off)
+
+	// the sequence numbers are read back from the snapshot, or rebuilt from the records without one
+	for _, snapshot := range []bool{true, false} {
+		require.NoError(t, log.Close())
+		if !snapshot {
+			require.NoError(t, os.Remove(path.Join(dir, producerSnapshotFile)))
+		}
+		log, err = NewLog(dir, Config{})
+		require.NoError(t, err)
+		off, err = produce(3, "d")
+		require.NoError(t, err)
+		require.Equal(t, uint64(3), off)
+		_, err = produce(6, "g")
+		require.IsType(t, api.ErrOutOfOrderSequence{}, err)
+	}
+	off, err = produce(5, "f")
+	require.NoError(t, err)
+	require.Equal(t, uint64(6), off)
+	require.NoError(t, log.Close())
+}
+
+func TestLogProducerSnapshotOnRoll(t *testing.T) {
+	dir, err := os.MkdirTemp("", "log-producer-snapshot-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	c := Config{}
+	c.Segment.MaxStoreBytes = 130
+	log, err := NewLog(dir, c)
+	require.NoError(t, err)
+
+	for seq := uint32(0); seq < 12; seq++ {
+		_, err := log.Append(&api.Record{Value: []byte("hello world"), ProducerId: 7, Sequence: seq})
+		require.NoError(t, err)
+	}
+	// sealing a segment snapshots the producers, up to the active segment
+	b, err := os.ReadFile(path.Join(dir, producerSnapshotFile))
+	require.NoError(t, err)
+	var snapshot producerSnapshot
+	require.NoError(t, json.Unmarshal(b, &snapshot))
+	require.NotZero(t, snapshot.Offset)
+	require.Equal(t, log.activeSegment.baseOffset, snapshot.Offset)
+
+	// after a crash, setup carries on from it
+	crash(t, log.activeSegment)
+	log, err = NewLog(dir, c)
+	require.NoError(t, err)
+	defer log.Close()
+	off, err := log.Append(&api.Record{Value: []byte("hello world"), ProducerId: 7, Sequence: 11})
+	require.NoError(t, err)
+	require.Equal(t, uint64(11), off)
+	off, err = log.Append(&api.Record{Value: []byte("hello world"), ProducerId: 7, Sequence: 12})
+	require.NoError(t, err)
+	require.Equal(t, uint64(12), off)
+}
+
+func TestLogTransactions(t *testing.T) {
+	dir, err := os.MkdirTemp("", "log-txn-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	log, err := NewLog(dir, Config{})
+	require.NoError(t, err)
+
+	produce := func(record *api.Record) {
+		t.Helper()
+		_, err := log.Append(record)
+		require.NoError(t, err)
+	}
+	txn := func(id uint64, seq uint32, value string) {
+		t.Helper()
+		produce(&api.Record{Value: []byte(value), ProducerId: id, Sequence: seq, Transactional: true})
+	}
+	readCommitted := func(off uint64) (uint64, error) {
+		record, err := log.ReadCommitted(off)
+		if err != nil {
+			return 0, err
+		}
+		return record.Offset, nil
+	}
+	appendRecords(t, log, 1)
+	txn(1, 0, "a")
+	txn(2, 0, "b")
+	appendRecords(t, log, 1)
+	require.Equal(t, uint64(1), log.LastStableOffset())
+	_, err = readCommitted(1)
+	require.Equal(t, api.ErrOffsetOutOfRange{Offset: 1}, err)
+
+	produce(&api.Record{ProducerId: 1, Control: api.Control_COMMIT})
+	require.Equal(t, uint64(2), log.LastStableOffset())
+	off, err := readCommitted(1)
+	require.NoError(t, err)
+	require.Equal(t, uint64(1), off)
+
+	txn(2, 1, "d")
+	produce(&api.Record{ProducerId: 2, Control: api.Control_ABORT})
+	txn(1, 1, "e")
+	appendRecords(t, log, 1)
+
+	// the aborted records and the markers are skipped, up to the open transaction
+	check := func() {
+		t.Helper()
+		require.Equal(t, uint64(7), log.LastStableOffset())
+		off, err := readCommitted(2)
+		require.NoError(t, err)
+		require.Equal(t, uint64(3), off)
+		_, err = readCommitted(4)
+		require.Equal(t, api.ErrOffsetOutOfRange{Offset: 7}, err)
+		// read uncommitted sees everything
+		record, err := log.Read(6)
+		require.NoError(t, err)
+		require.Equal(t, api.Control_ABORT, record.Control)
+	}
+	check()
+	// the transactions are read back from the snapshot, or rebuilt from the records without one
+	for _, snapshot := range []bool{true, false} {
+		require.NoError(t, log.Close())
+		if !snapshot {
+			require.NoError(t, os.Remove(path.Join(dir, producerSnapshotFile)))
+		}
+		log, err = NewLog(dir, Config{})
+		require.NoError(t, err)
+		check()
+	}
+
+	errs := make(chan error, 1)
+	go func() { errs <- log.WaitForStableOffset(context.Background(), 7) }()
+	produce(&api.Record{ProducerId: 1, Control: api.Control_COMMIT})
+	require.NoError(t, <-errs)
+	off, err = readCommitted(4)
+	require.NoError(t, err)
+	require.Equal(t, uint64(7), off)
+	require.NoError(t, log.Close())
+}
+
+func TestLogAbortedTxnsPruned(t *testing.T) {
+	dir, err := os.MkdirTemp("", "log-txn-pruned-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	c := Config{}
+	c.Segment.MaxStoreBytes = 130
+	log, err := NewLog(dir, c)
+	require.NoError(t, err)
+	defer log.Close()
+	aborted := func() int {
+		log.mu.Lock()
+		defer log.mu.Unlock()
+		return len(log.aborted)
+	}
+
+	_, err = log.Append(&api.Record{Value: []byte("aborted"), ProducerId: 1, Transactional: true})
+	require.NoError(t, err)
+	_, err = log.Append(&api.Record{ProducerId: 1, Control: api.Control_ABORT})
+	require.NoError(t, err)
+	// seals the segment the transaction's in
+	appendRecords(t, log, 10)
+	require.Equal(t, 1, aborted())
+	// compaction drops the transaction's records, and forgets it
+	require.NoError(t, log.Compact())
+	require.Equal(t, 0, aborted())
+	record, err := log.ReadCommitted(0)
+	require.NoError(t, err)
+	require.Equal(t, uint64(2), record.Offset)
+}
+
+func TestLogAppendExpect(t *testing.T) {
+	dir, err := os.MkdirTemp("", "log-expect-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	log, err := NewLog(dir, Config{})
+	require.NoError(t, err)
+	defer log.Close()
+
+	appendRecords(t, log, 2)
+	off, err := log.AppendExpect(&api.Record{Value: []byte("a")}, Expectation{NextOffset: proto.Uint64(2)})
+	require.NoError(t, err)
+	require.Equal(t, uint64(2), off)
+	// another writer got there first
+	_, err = log.AppendExpect(&api.Record{Value: []byte("b")}, Expectation{NextOffset: proto.Uint64(2)})
+	require.Equal(t, api.ErrConflict{Expected: 2, Actual: 3}, err)
+
+	key := []byte("account-1")
+	_, err = log.AppendExpect(&api.Record{Value: []byte("opened")}, Expectation{KeyOffset: proto.Int64(-1)})
+	require.Equal(t, api.ErrExpectedKey{}, err)
+	off, err = log.AppendExpect(&api.Record{Key: key, Value: []byte("opened")}, Expectation{KeyOffset: proto.Int64(-1)})
+	require.NoError(t, err)
+	require.Equal(t, uint64(3), off)
+	_, err = log.AppendExpect(&api.Record{Key: key, Value: []byte("opened")}, Expectation{KeyOffset: proto.Int64(-1)})
+	require.Equal(t, api.ErrConflict{Key: key, Expected: -1, Actual: 3}, err)
+	appendRecords(t, log, 1)
+	off, err = log.AppendExpect(&api.Record{Key: key, Value: []byte("deposited")}, Expectation{KeyOffset: proto.Int64(3)})
+	require.NoError(t, err)
+	require.Equal(t, uint64(5), off)
+
+	// of concurrent writers expecting the same offset, one wins
+	var wg sync.WaitGroup
+	errs := make(chan error, 8)
+	for i := 0; i < cap(errs); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := log.AppendExpect(&api.Record{Key: key, Value: []byte("withdrew")}, Expectation{KeyOffset: proto.Int64(5)})
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	var won int
+	for err := range errs {
+		if err == nil {
+			won++
+			continue
+		}
+		require.IsType(t, api.ErrConflict{}, err)
+	}
+	require.Equal(t, 1, won)
+
+	// a transaction's records only count once it's committed
+	produce := func(record *api.Record) {
+		t.Helper()
+		_, err := log.Append(record)
+		require.NoError(t, err)
+	}
+	produce(&api.Record{Key: key, Value: []byte("aborted"), ProducerId: 7, Transactional: true})
+	produce(&api.Record{ProducerId: 7, Control: api.Control_ABORT})
+	off, err = log.AppendExpect(&api.Record{Key: key, Value: []byte("withdrew")}, Expectation{KeyOffset: proto.Int64(6)})
+	require.NoError(t, err)
+	require.Equal(t, uint64(9), off)
+	produce(&api.Record{Key: key, Value: []byte("committed"), ProducerId: 7, Sequence: 1, Transactional: true})
+	produce(&api.Record{ProducerId: 7, Control: api.Control_COMMIT})
+	_, err = log.AppendExpect(&api.Record{Key: key, Value: []byte("withdrew")}, Expectation{KeyOffset: proto.Int64(9)})
+	require.Equal(t, api.ErrConflict{Key: key, Expected: 9, Actual: 10}, err)
+
+	// and the keys are found the same way when the log's read back
+	require.NoError(t, log.Close())
+	log, err = NewLog(dir, Config{})
+	require.NoError(t, err)
+	off, err = log.AppendExpect(&api.Record{Key: key, Value: []byte("closed")}, Expectation{KeyOffset: proto.Int64(10)})
+	require.NoError(t, err)
+	require.Equal(t, uint64(12), off)
+	require.NoError(t, log.Close())
+}
+
+func TestLogGroupCommit(t *testing.T) {
+	dir, err := os.MkdirTemp("", "log-group-commit-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	c := Config{}
+	c.Segment.MaxStoreBytes = 1024
+	c.Durability.Mode = SyncAlways
+	log, err := NewLog(dir, c)
+	require.NoError(t, err)
+
+	const producers, records = 16, 50
+	offsets := make(chan uint64, producers*records)
+	errs := make(chan error, producers)
+	for i := 0; i < producers; i++ {
+		go func() {
+			for j := 0; j < records; j++ {
+				off, err := log.Append(&api.Record{Value: []byte("hello world")})
+				if err != nil {
+					errs <- err
+					return
+				}
+				offsets <- off
+			}
+			errs <- nil
+		}()
+	}
+	for i := 0; i < producers; i++ {
+		require.NoError(t, <-errs)
+	}
+	close(offsets)
+
+	// every producer got its own offset and the log has no gaps
+	seen := make(map[uint64]bool)
+	for off := range offsets {
+		require.False(t, seen[off])
+		seen[off] = true
+	}
+	for off := uint64(0); off < producers*records; off++ {
+		read, err := log.Read(off)
+		require.NoError(t, err)
+		require.Equal(t, off, read.Offset)
+	}
+	// the appends were committed together, with an fsync for each group instead of each append
+	var syncs uint64
+	for _, s := range log.loadSegments() {
+		syncs += s.store.syncs.Load()
+	}
+	require.Less(t, syncs, uint64(producers*records/2))
+	require.NoError(t, log.Close())
+	_, err = log.Append(&api.Record{Value: []byte("hello world")})
+	require.Equal(t, ErrClosed, err)
+}
+
+func BenchmarkAppend(b *testing.B) {
+	for _, mode := range []struct {
+		name string
+		mode SyncMode
+	}{{"os", SyncOS}, {"always", SyncAlways}} {
+		for _, producers := range []int{1, 16, 256} {
+			b.Run(fmt.Sprintf("sync=%s/producers=%d", mode.name, producers), func(b *testing.B) {
+				c := Config{}
+				c.Segment.MaxStoreBytes = 1 << 30
+				c.Durability.Mode = mode.mode
+				benchmarkAppend(b, c, producers, 0)
+			})
+		}
+	}
+}
+
+// BenchmarkAppendRolling appends to the default small segments, so the log rolls every few appends, with
+// and without an idempotent producer to snapshot.
+func BenchmarkAppendRolling(b *testing.B) {
+	for _, mode := range []struct {
+		name string
+		mode SyncMode
+	}{{"os", SyncOS}, {"always", SyncAlways}} {
+		for _, producerID := range []uint64{0, 1} {
+			b.Run(fmt.Sprintf("sync=%s/idempotent=%t", mode.name, producerID != 0), func(b *testing.B) {
+				c := Config{}
+				c.Durability.Mode = mode.mode
+				benchmarkAppend(b, c, 1, producerID)
+			})
+		}
+	}
+}
+
+// benchmarkAppend appends b.N records from concurrent producers, numbered by the idempotent producer
+// producerID if it isn't 0, which only makes sense for a single producer.
+func benchmarkAppend(b *testing.B, c Config, producers int, producerID uint64) {
+	dir, err := os.MkdirTemp("", "log-append-bench")
+	require.NoError(b, err)
+	defer os.RemoveAll(dir)
+	log, err := NewLog(dir, c)
+	require.NoError(b, err)
+	defer log.Close()
+
+	value := make([]byte, 256)
+	b.SetBytes(int64(len(value)))
+	b.ResetTimer()
+	var wg sync.WaitGroup
+	for i := 0; i < producers; i++ {
+		n := b.N / producers
+		if i < b.N%producers {
+			n++
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < n; j++ {
+				record := &api.Record{Value: value, ProducerId: producerID, Sequence: uint32(j)}
+				if _, err := log.Append(record); err != nil {
+					b.Error(err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestLogReadWhileAppending(t *testing.T) {
+	dir, err := os.MkdirTemp("", "log-read-append-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	c := Config{}
+	c.Segment.MaxStoreBytes = 1024
+	log, err := NewLog(dir, c)
+	require.NoError(t, err)
+	defer log.Close()
+
+	const records = 500
+	errs := make(chan error, 5)
+	go func() {
+		for i := 0; i < records; i++ {
+			if _, err := log.Append(&api.Record{Value: []byte(fmt.Sprintf("record %d", i))}); err != nil {
+				errs <- err
+				return
+			}
+		}
+		errs <- nil
+	}()
+	// tailing readers see every record as soon as it's appended, across segment rolls
+	for i := 0; i < 4; i++ {
+		go func() {
+			for off := uint64(0); off < records; {
+				read, err := log.Read(off)
+				if _, ok := err.(api.ErrOffsetOutOfRange); ok {
+					runtime.Gosched()
+					continue
+				}
+				if err == nil && string(read.Value) != fmt.Sprintf("record %d", off) {
+					err = fmt.Errorf("read %q at offset %d", read.Value, off)
+				}
+				if err != nil {
+					errs <- err
+					return
+				}
+				off++
+			}
+			errs <- nil
+		}()
+	}
+	for i := 0; i < cap(errs); i++ {
+		require.NoError(t, <-errs)
+	}
+	require.Greater(t, len(log.loadSegments()), 1)
+}
@@ -0,0 +1,483 @@
+/*
+	A LogManager keeps the topics in directories of their own named after them, under the manager's. A topic
+	is split into partitions, each a log of its own in a directory named after its number, under the topic's.
+	A topic's directory also holds a topic.json with the config overrides the topic was created with, applied
+	over the manager's config: the fields an override leaves zero keep the manager's value.
+	Topics found on disk when the manager starts are only opened once they're first used. Topics from before
+	partitions have their segments right in the topic's directory: they're moved into partition 0. So is a log
+	from before topics, with its segments right in the manager's directory: it becomes DefaultTopic.
+*/
+
+package log
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sant470/distlogs/api/v1"
+)
+
+const (
+	// DefaultTopic is where records addressed to no topic in particular go. The manager always has it,
+	// with a single partition.
+	DefaultTopic    = "default"
+	topicConfigFile = "topic.json"
+)
+
+var ErrInvalidPartitions = errors.New("a topic needs at least one partition")
+
+type LogManager struct {
+	Dir    string
+	Config Config
+	// Partitioner picks the partition of the records appended without one. NewLogManager sets it to hash
+	// keys, and to go round-robin for the records without a key.
+	Partitioner Partitioner
+	// TxnTimeout is how long a transaction can stay in progress before it's aborted, none time out if it's
+	// zero. NewLogManager sets it to DefaultTxnTimeout.
+	TxnTimeout time.Duration
+	mu         sync.Mutex
+	// topics has every topic's overrides, and its partitions' logs once it's been opened
+	topics map[string]*topic
+	closed bool
+	// consumer groups' committed offsets, see offsets.go
+	offsetsMu sync.Mutex
+	offsets   map[string]uint64
+	// the producers' transactions, see txnmanager.go
+	txnMu sync.Mutex
+	txns  map[uint64]*transaction
+}
+
+type topic struct {
+	overrides  Config
+	partitions int
+	logs       []*Log
+}
+
+func NewLogManager(dir string, c Config) (*LogManager, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	m := &LogManager{
+		Dir:         dir,
+		Config:      c,
+		Partitioner: KeyHash{Keyless: &RoundRobin{}},
+		TxnTimeout:  DefaultTxnTimeout,
+		topics:      make(map[string]*topic),
+	}
+	if err := migrateLog(dir); err != nil {
+		return nil, err
+	}
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, file := range files {
+		if !file.IsDir() {
+			continue
+		}
+		// a directory without a config is a topic whose creation was cut short
+		b, err := os.ReadFile(path.Join(dir, file.Name(), topicConfigFile))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		t := &topic{}
+		if err = json.Unmarshal(b, &t.overrides); err != nil {
+			return nil, err
+		}
+		if t.partitions, err = migrateTopic(path.Join(dir, file.Name())); err != nil {
+			return nil, err
+		}
+		m.topics[file.Name()] = t
+	}
+	if _, ok := m.topics[DefaultTopic]; !ok {
+		if err = m.CreateTopic(DefaultTopic, 1, Config{}); err != nil {
+			return nil, err
+		}
+	}
+	if err = m.loadOffsets(); err != nil {
+		return nil, err
+	}
+	if err = m.initProducers(); err != nil {
+		return nil, err
+	}
+	if err = m.loadTxns(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// migrateTopic moves the segments a topic from before partitions has in its directory into partition 0,
+// and returns how many partitions the topic has.
+func migrateTopic(dir string) (int, error) {
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		return 0, err
+	}
+	var partitions int
+	for _, file := range files {
+		if !file.IsDir() {
+			continue
+		}
+		if _, err := strconv.ParseUint(file.Name(), 10, 32); err == nil {
+			partitions++
+		}
+	}
+	moved, err := moveLog(dir, path.Join(dir, "0"))
+	if err != nil {
+		return 0, err
+	}
+	// so is what a compaction cut short left behind
+	if moved {
+		if err = os.RemoveAll(path.Join(dir, compactingDir)); err != nil {
+			return 0, err
+		}
+	}
+	return max(partitions, 1), nil
+}
+
+// migrateLog moves the log from before topics that has its segments in the manager's directory into
+// DefaultTopic's partition.
+func migrateLog(dir string) error {
+	topic := path.Join(dir, DefaultTopic)
+	moved, err := moveLog(dir, path.Join(topic, "0"))
+	if err != nil || !moved {
+		return err
+	}
+	// what a compaction cut short left behind goes too, unless it's a topic of that name
+	compacting := path.Join(dir, compactingDir)
+	if _, err = os.Stat(path.Join(compacting, topicConfigFile)); os.IsNotExist(err) {
+		if err = os.RemoveAll(compacting); err != nil {
+			return err
+		}
+	} else if err != nil {
+		return err
+	}
+	if _, err = os.Stat(path.Join(topic, topicConfigFile)); os.IsNotExist(err) {
+		return writeTopicConfig(topic, Config{})
+	}
+	return err
+}
+
+// moveLog moves the files of the log in dir into partition, and reports whether it found any. A migration
+// that was cut short left some of the files behind, they go to the same place. A file the partition already
+// has is never overwritten: the partition has a log of its own, and the migration fails.
+func moveLog(dir, partition string) (bool, error) {
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		return false, err
+	}
+	var logFiles []string
+	for _, file := range files {
+		if file.IsDir() {
+			continue
+		}
+		switch path.Ext(file.Name()) {
+		case ".store", ".index", ".timeindex":
+			logFiles = append(logFiles, file.Name())
+		}
+		if file.Name() == producerSnapshotFile {
+			logFiles = append(logFiles, file.Name())
+		}
+	}
+	if len(logFiles) == 0 {
+		return false, nil
+	}
+	if err = os.MkdirAll(partition, 0755); err != nil {
+		return false, err
+	}
+	for _, name := range logFiles {
+		dst := path.Join(partition, name)
+		if _, err = os.Stat(dst); err == nil {
+			return false, fmt.Errorf("migrating %s: %s already exists", path.Join(dir, name), dst)
+		} else if !os.IsNotExist(err) {
+			return false, err
+		}
+		if err = os.Rename(path.Join(dir, name), dst); err != nil {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// CreateTopic creates the topic with the given number of partitions and config overrides.
+func (m *LogManager) CreateTopic(name string, partitions int, overrides Config) error {
+	if !validTopic(name) {
+		return api.ErrInvalidTopic{Topic: name, Reason: "not a valid topic name"}
+	}
+	return m.createTopic(name, partitions, overrides)
+}
+
+func validTopic(name string) bool {
+	return name != "" && name != "." && name != ".." && !strings.ContainsAny(name, `/\`) &&
+		!strings.HasPrefix(name, internalTopicPrefix)
+}
+
+func (m *LogManager) createTopic(name string, partitions int, overrides Config) error {
+	if partitions < 1 {
+		return ErrInvalidPartitions
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.closed {
+		return ErrClosed
+	}
+	if _, ok := m.topics[name]; ok {
+		return api.ErrTopicExists{Topic: name}
+	}
+	dir := path.Join(m.Dir, name)
+	for i := 0; i < partitions; i++ {
+		if err := os.MkdirAll(path.Join(dir, strconv.Itoa(i)), 0755); err != nil {
+			return err
+		}
+	}
+	// the config goes last, once it's there the topic exists
+	if err := writeTopicConfig(dir, overrides); err != nil {
+		return err
+	}
+	t := &topic{overrides: overrides, partitions: partitions}
+	m.topics[name] = t
+	return m.open(name, t)
+}
+
+// writeTopicConfig writes the topic's overrides to the config file in its directory, in one go.
+func writeTopicConfig(dir string, overrides Config) error {
+	b, err := json.Marshal(overrides)
+	if err != nil {
+		return err
+	}
+	tmp := path.Join(dir, topicConfigFile+".tmp")
+	if err = os.WriteFile(tmp, b, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path.Join(dir, topicConfigFile))
+}
+
+// Partition returns the log of the topic's partition, opening the topic if it isn't yet. An empty topic
+// stands for DefaultTopic.
+func (m *LogManager) Partition(name string, partition uint32) (*Log, error) {
+	if name == "" {
+		name = DefaultTopic
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.closed {
+		return nil, ErrClosed
+	}
+	t, ok := m.topics[name]
+	if !ok {
+		return nil, api.ErrTopicNotFound{Topic: name}
+	}
+	if uint64(partition) >= uint64(t.partitions) {
+		return nil, api.ErrPartitionNotFound{Topic: name, Partition: partition}
+	}
+	if t.logs == nil {
+		if err := m.open(name, t); err != nil {
+			return nil, err
+		}
+	}
+	return t.logs[partition], nil
+}
+
+// Partitions returns how many partitions the topic has.
+func (m *LogManager) Partitions(name string) (int, error) {
+	if name == "" {
+		name = DefaultTopic
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	t, ok := m.topics[name]
+	if !ok {
+		return 0, api.ErrTopicNotFound{Topic: name}
+	}
+	return t.partitions, nil
+}
+
+// open opens the logs of the topic's partitions. It's called with m.mu held.
+func (m *LogManager) open(name string, t *topic) error {
+	c := overlay(m.Config, t.overrides)
+	// overrides can't turn off the retention the manager's config sets
+	if retainedTopics[name] {
+		c.Retention.MaxAge, c.Retention.MaxBytes = 0, 0
+	}
+	logs := make([]*Log, t.partitions)
+	for i := range logs {
+		dir := path.Join(m.Dir, name, strconv.Itoa(i))
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+		l, err := NewLog(dir, c)
+		if err != nil {
+			for _, l := range logs[:i] {
+				l.Close()
+			}
+			return err
+		}
+		logs[i] = l
+	}
+	t.logs = logs
+	return nil
+}
+
+// Topics returns the names of the topics, sorted.
+func (m *LogManager) Topics() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	names := make([]string, 0, len(m.topics))
+	for name := range m.topics {
+		if !strings.HasPrefix(name, internalTopicPrefix) {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+	return names
+}
+
+// DeleteTopic deletes the groups' commits for the topic, closes the logs of its partitions and removes it
+// from disk. DefaultTopic can't be deleted.
+func (m *LogManager) DeleteTopic(name string) error {
+	if !validTopic(name) {
+		return api.ErrInvalidTopic{Topic: name, Reason: "not a valid topic name"}
+	}
+	if name == DefaultTopic {
+		return api.ErrInvalidTopic{Topic: name, Reason: "the default topic can't be deleted"}
+	}
+	offsets, err := m.Partition(offsetsTopic, 0)
+	if err != nil {
+		return err
+	}
+	// a topic created again with the same name doesn't get the commits back
+	m.offsetsMu.Lock()
+	defer m.offsetsMu.Unlock()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	t, ok := m.topics[name]
+	if !ok {
+		return api.ErrTopicNotFound{Topic: name}
+	}
+	if err = m.deleteOffsets(offsets, name); err != nil {
+		return err
+	}
+	delete(m.topics, name)
+	for _, l := range t.logs {
+		if err := l.Close(); err != nil {
+			return err
+		}
+	}
+	return os.RemoveAll(path.Join(m.Dir, name))
+}
+
+// Close closes the logs of every topic that's been opened.
+func (m *LogManager) Close() error {
+	// the transactions in progress are left to be aborted when the manager starts again
+	m.txnMu.Lock()
+	for _, t := range m.txns {
+		if t.timeout != nil {
+			t.timeout.Stop()
+		}
+	}
+	m.txnMu.Unlock()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.closed {
+		return nil
+	}
+	m.closed = true
+	for _, t := range m.topics {
+		for _, l := range t.logs {
+			if err := l.Close(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Append appends the record to the given partition of the topic, or the one the manager's partitioner
+// picks if partition is nil, and returns the partition and the record's offset in it. See Log.Append.
+func (m *LogManager) Append(topic string, partition *uint32, record *api.Record) (uint32, uint64, error) {
+	return m.AppendExpect(topic, partition, record, nil, nil)
+}
+
+// AppendExpect appends the record like Append, if it goes to expectedOffset and the latest record with its
+// key is at expectedKeyOffset, either left nil to expect nothing. See Log.AppendExpect.
+func (m *LogManager) AppendExpect(
+	topic string, partition *uint32, record *api.Record, expectedOffset *uint64, expectedKeyOffset *int64,
+) (uint32, uint64, error) {
+	l, picked, err := m.pick(topic, partition, record)
+	if err != nil {
+		return 0, 0, err
+	}
+	off, err := l.AppendExpect(record, Expectation{NextOffset: expectedOffset, KeyOffset: expectedKeyOffset})
+	if err != nil {
+		return 0, 0, err
+	}
+	return picked, off, nil
+}
+
+// pick returns the log of the partition the record goes to, and its number.
+func (m *LogManager) pick(topic string, partition *uint32, record *api.Record) (*Log, uint32, error) {
+	var p Partitioner = m.Partitioner
+	if partition != nil {
+		p = Explicit(*partition)
+	}
+	n, err := m.Partitions(topic)
+	if err != nil {
+		return nil, 0, err
+	}
+	picked := uint32(p.Partition(record, n))
+	l, err := m.Partition(topic, picked)
+	if err != nil {
+		return nil, 0, err
+	}
+	return l, picked, nil
+}
+
+// Read reads the record at off from the topic's partition, see Log.Read.
+func (m *LogManager) Read(topic string, partition uint32, off uint64) (*api.Record, error) {
+	l, err := m.Partition(topic, partition)
+	if err != nil {
+		return nil, err
+	}
+	return l.Read(off)
+}
+
+// WaitForOffset waits for the record at off to be appended to the topic's partition, see Log.WaitForOffset.
+func (m *LogManager) WaitForOffset(ctx context.Context, topic string, partition uint32, off uint64) error {
+	l, err := m.Partition(topic, partition)
+	if err != nil {
+		return err
+	}
+	return l.WaitForOffset(ctx, off)
+}
+
+// overlay returns c with the fields overrides sets replaced.
+func overlay(c, overrides Config) Config {
+	overlayFields(reflect.ValueOf(&c).Elem(), reflect.ValueOf(overrides))
+	return c
+}
+
+func overlayFields(dst, src reflect.Value) {
+	for i := 0; i < src.NumField(); i++ {
+		field := src.Field(i)
+		if field.Kind() == reflect.Struct {
+			overlayFields(dst.Field(i), field)
+			continue
+		}
+		if !field.IsZero() {
+			dst.Field(i).Set(field)
+		}
+	}
+}
@@ -0,0 +1,361 @@
+package log
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/sant470/distlogs/api/v1"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLogManager(t *testing.T) {
+	dir, err := os.MkdirTemp("", "log-manager-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	c := Config{}
+	c.Segment.MaxStoreBytes = 1024
+	m, err := NewLogManager(dir, c)
+	require.NoError(t, err)
+	require.Equal(t, []string{DefaultTopic}, m.Topics())
+
+	overrides := Config{}
+	overrides.Segment.MaxStoreBytes = 150
+	overrides.Compression.Codec = Gzip
+	require.NoError(t, m.CreateTopic("orders", 2, overrides))
+	orders, err := m.Partition("orders", 1)
+	require.NoError(t, err)
+	require.Equal(t, uint64(150), orders.Config.Segment.MaxStoreBytes)
+	require.Equal(t, Gzip, orders.Config.Compression.Codec)
+	require.Equal(t, api.ErrTopicExists{Topic: "orders"}, m.CreateTopic("orders", 1, Config{}))
+	for _, name := range []string{"", "..", "a/b", offsetsTopic} {
+		require.IsType(t, api.ErrInvalidTopic{}, m.CreateTopic(name, 1, Config{}))
+	}
+	require.Equal(t, ErrInvalidPartitions, m.CreateTopic("payments", 0, Config{}))
+	require.NoError(t, m.CreateTopic("payments", 1, Config{}))
+	require.Equal(t, []string{DefaultTopic, "orders", "payments"}, m.Topics())
+
+	// each partition has offsets of its own
+	one := uint32(1)
+	for _, partition := range []*uint32{nil, &one, &one} {
+		_, _, err = m.Append("orders", partition, &api.Record{Value: []byte("orders")})
+		require.NoError(t, err)
+	}
+	record, err := m.Read("orders", 1, 1)
+	require.NoError(t, err)
+	require.Equal(t, "orders", string(record.Value))
+	_, err = m.Read("orders", 0, 1)
+	require.IsType(t, api.ErrOffsetOutOfRange{}, err)
+	_, err = m.Read("orders", 2, 0)
+	require.Equal(t, api.ErrPartitionNotFound{Topic: "orders", Partition: 2}, err)
+	_, err = m.Read("shipments", 0, 0)
+	require.Equal(t, api.ErrTopicNotFound{Topic: "shipments"}, err)
+
+	producer, err := m.InitProducer()
+	require.NoError(t, err)
+	require.NotZero(t, producer)
+
+	require.NoError(t, m.DeleteTopic("payments"))
+	require.IsType(t, api.ErrInvalidTopic{}, m.DeleteTopic(DefaultTopic))
+	require.NoError(t, m.Close())
+	_, err = m.Partition("orders", 0)
+	require.Equal(t, ErrClosed, err)
+
+	// topics are found on disk, and only opened once they're used
+	m, err = NewLogManager(dir, c)
+	require.NoError(t, err)
+	defer m.Close()
+	require.Equal(t, []string{DefaultTopic, "orders"}, m.Topics())
+	require.Nil(t, m.topics["orders"].logs)
+	n, err := m.Partitions("orders")
+	require.NoError(t, err)
+	require.Equal(t, 2, n)
+	orders, err = m.Partition("orders", 1)
+	require.NoError(t, err)
+	require.Equal(t, uint64(150), orders.Config.Segment.MaxStoreBytes)
+	require.Equal(t, Gzip, orders.Config.Compression.Codec)
+	record, err = m.Read("orders", 1, 1)
+	require.NoError(t, err)
+	require.Equal(t, "orders", string(record.Value))
+	// producer IDs aren't handed out again
+	next, err := m.InitProducer()
+	require.NoError(t, err)
+	require.Greater(t, next, producer)
+}
+
+func TestLogManagerMigratesUnpartitionedTopics(t *testing.T) {
+	dir, err := os.MkdirTemp("", "log-manager-migrate-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	// a topic from before partitions has its segments next to its config
+	topic := path.Join(dir, "orders")
+	require.NoError(t, os.Mkdir(topic, 0755))
+	l, err := NewLog(topic, Config{})
+	require.NoError(t, err)
+	appendRecords(t, l, 3)
+	require.NoError(t, l.Close())
+	require.NoError(t, os.WriteFile(path.Join(topic, topicConfigFile), []byte("{}"), 0644))
+
+	m, err := NewLogManager(dir, Config{})
+	require.NoError(t, err)
+	defer m.Close()
+	n, err := m.Partitions("orders")
+	require.NoError(t, err)
+	require.Equal(t, 1, n)
+	for off := uint64(0); off < 3; off++ {
+		_, err := m.Read("orders", 0, off)
+		require.NoError(t, err)
+	}
+	_, err = os.Stat(path.Join(topic, "0.store"))
+	require.True(t, os.IsNotExist(err))
+}
+
+func TestLogManagerMigratesRootLog(t *testing.T) {
+	dir, err := os.MkdirTemp("", "log-manager-migrate-root-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	// a log from before topics has its segments in the manager's directory
+	l, err := NewLog(dir, Config{})
+	require.NoError(t, err)
+	appendRecords(t, l, 3)
+	require.NoError(t, l.Close())
+
+	m, err := NewLogManager(dir, Config{})
+	require.NoError(t, err)
+	require.Equal(t, []string{DefaultTopic}, m.Topics())
+	for off := uint64(0); off < 3; off++ {
+		_, err := m.Read(DefaultTopic, 0, off)
+		require.NoError(t, err)
+	}
+	_, err = os.Stat(path.Join(dir, "0.store"))
+	require.True(t, os.IsNotExist(err))
+	require.NoError(t, m.Close())
+
+	// and it's still there once the manager starts again
+	m, err = NewLogManager(dir, Config{})
+	require.NoError(t, err)
+	defer m.Close()
+	_, err = m.Read(DefaultTopic, 0, 2)
+	require.NoError(t, err)
+}
+
+func TestLogManagerCommittedOffsets(t *testing.T) {
+	dir, err := os.MkdirTemp("", "log-manager-offsets-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	m, err := NewLogManager(dir, Config{})
+	require.NoError(t, err)
+	require.NoError(t, m.CreateTopic("orders", 2, Config{}))
+
+	_, err = m.CommittedOffset("billing", "orders", 1)
+	require.Equal(t, api.ErrNoCommittedOffset{Group: "billing", Topic: "orders", Partition: 1}, err)
+	require.NoError(t, m.CommitOffset("billing", "orders", 1, 3))
+	require.NoError(t, m.CommitOffset("billing", "orders", 1, 5))
+	require.NoError(t, m.CommitOffset("shipping", "orders", 1, 4))
+	require.NoError(t, m.CommitOffset("billing", "", 0, 7))
+	require.Equal(t, api.ErrInvalidGroup{}, m.CommitOffset("", "orders", 1, 1))
+	require.Equal(t, api.ErrPartitionNotFound{Topic: "orders", Partition: 2}, m.CommitOffset("billing", "orders", 2, 1))
+
+	check := func(m *LogManager) {
+		t.Helper()
+		for _, tc := range []struct {
+			group, topic string
+			partition    uint32
+			off          uint64
+		}{
+			{"billing", "orders", 1, 5},
+			{"shipping", "orders", 1, 4},
+			{"billing", DefaultTopic, 0, 7},
+		} {
+			off, err := m.CommittedOffset(tc.group, tc.topic, tc.partition)
+			require.NoError(t, err)
+			require.Equal(t, tc.off, off)
+		}
+		_, err = m.CommittedOffset("billing", "orders", 0)
+		require.IsType(t, api.ErrNoCommittedOffset{}, err)
+	}
+	check(m)
+	// the commits are read back from the offsets topic
+	require.NoError(t, m.Close())
+	m, err = NewLogManager(dir, Config{})
+	require.NoError(t, err)
+	check(m)
+
+	// a topic created again with the same name starts without commits, before and after a restart
+	require.NoError(t, m.DeleteTopic("orders"))
+	require.NoError(t, m.CreateTopic("orders", 2, Config{}))
+	deleted := func(m *LogManager) {
+		t.Helper()
+		_, err := m.CommittedOffset("billing", "orders", 1)
+		require.Equal(t, api.ErrNoCommittedOffset{Group: "billing", Topic: "orders", Partition: 1}, err)
+		off, err := m.CommittedOffset("billing", "", 0)
+		require.NoError(t, err)
+		require.Equal(t, uint64(7), off)
+	}
+	deleted(m)
+	require.NoError(t, m.Close())
+	m, err = NewLogManager(dir, Config{})
+	require.NoError(t, err)
+	defer m.Close()
+	deleted(m)
+}
+
+func TestLogManagerCommittedOffsetsRetained(t *testing.T) {
+	dir, err := os.MkdirTemp("", "log-manager-offsets-retention-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	c := Config{}
+	c.Segment.MaxStoreBytes = 150
+	c.Retention.MaxAge = 50 * time.Millisecond
+	m, err := NewLogManager(dir, c)
+	require.NoError(t, err)
+
+	// commits outlive the retention the manager's topics get
+	for i := uint64(0); i < 10; i++ {
+		require.NoError(t, m.CommitOffset(fmt.Sprintf("group %d", i), "", 0, i))
+	}
+	offsets, err := m.Partition(offsetsTopic, 0)
+	require.NoError(t, err)
+	require.Equal(t, SyncAlways, offsets.Config.Durability.Mode)
+	segments := len(offsets.loadSegments())
+	require.Greater(t, segments, 1)
+	time.Sleep(60 * time.Millisecond)
+	require.NoError(t, offsets.EnforceRetention())
+	require.Len(t, offsets.loadSegments(), segments)
+	require.NoError(t, m.Close())
+	m, err = NewLogManager(dir, c)
+	require.NoError(t, err)
+	defer m.Close()
+	off, err := m.CommittedOffset("group 0", "", 0)
+	require.NoError(t, err)
+	require.Equal(t, uint64(0), off)
+}
+
+func TestLogManagerTransactions(t *testing.T) {
+	dir, err := os.MkdirTemp("", "log-manager-txn-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	m, err := NewLogManager(dir, Config{})
+	require.NoError(t, err)
+	require.NoError(t, m.CreateTopic("orders", 1, Config{}))
+	require.NoError(t, m.CreateTopic("inventory", 1, Config{}))
+	producer, err := m.InitProducer()
+	require.NoError(t, err)
+
+	require.Equal(t, api.ErrInvalidProducer{}, m.BeginTxn(0))
+	require.Equal(t, api.ErrNoTxn{ProducerID: producer}, m.CommitTxn(producer))
+	_, _, err = m.AppendTxn("orders", nil, &api.Record{ProducerId: producer}, nil, nil)
+	require.Equal(t, api.ErrNoTxn{ProducerID: producer}, err)
+
+	// a transaction's records become visible in every partition at once
+	require.NoError(t, m.BeginTxn(producer))
+	require.Equal(t, api.ErrTxnInProgress{ProducerID: producer}, m.BeginTxn(producer))
+	for _, topic := range []string{"orders", "inventory"} {
+		_, _, err = m.AppendTxn(topic, nil, &api.Record{Value: []byte(topic), ProducerId: producer}, nil, nil)
+		require.NoError(t, err)
+		_, err = m.ReadCommitted(topic, 0, 0)
+		require.Equal(t, api.ErrOffsetOutOfRange{Offset: 0}, err)
+	}
+	require.NoError(t, m.CommitTxn(producer))
+	for _, topic := range []string{"orders", "inventory"} {
+		record, err := m.ReadCommitted(topic, 0, 0)
+		require.NoError(t, err)
+		require.Equal(t, topic, string(record.Value))
+		lso, err := m.LastStableOffset(topic, 0)
+		require.NoError(t, err)
+		require.Equal(t, uint64(2), lso)
+	}
+
+	require.NoError(t, m.BeginTxn(producer))
+	_, _, err = m.AppendTxn("orders", nil, &api.Record{Value: []byte("aborted"), ProducerId: producer, Sequence: 1}, nil, nil)
+	require.NoError(t, err)
+	require.NoError(t, m.AbortTxn(producer))
+	_, err = m.ReadCommitted("orders", 0, 2)
+	require.Equal(t, api.ErrOffsetOutOfRange{Offset: 4}, err)
+
+	// a transaction left open is aborted when the manager starts again
+	require.NoError(t, m.BeginTxn(producer))
+	_, _, err = m.AppendTxn("inventory", nil, &api.Record{Value: []byte("left open"), ProducerId: producer, Sequence: 1}, nil, nil)
+	require.NoError(t, err)
+	require.NoError(t, m.Close())
+	m, err = NewLogManager(dir, Config{})
+	require.NoError(t, err)
+	defer m.Close()
+	_, err = m.ReadCommitted("inventory", 0, 2)
+	require.Equal(t, api.ErrOffsetOutOfRange{Offset: 4}, err)
+
+	// a transaction left in progress past the timeout is aborted
+	m.TxnTimeout = 10 * time.Millisecond
+	require.NoError(t, m.BeginTxn(producer))
+	_, _, err = m.AppendTxn("orders", nil, &api.Record{Value: []byte("timed out"), ProducerId: producer, Sequence: 2}, nil, nil)
+	require.NoError(t, err)
+	require.Eventually(t, func() bool {
+		lso, err := m.LastStableOffset("orders", 0)
+		return err == nil && lso == 6
+	}, time.Second, 5*time.Millisecond)
+	require.Equal(t, api.ErrNoTxn{ProducerID: producer}, m.CommitTxn(producer))
+	_, err = m.ReadCommitted("orders", 0, 2)
+	require.Equal(t, api.ErrOffsetOutOfRange{Offset: 6}, err)
+}
+
+func TestLogManagerTxnsRetained(t *testing.T) {
+	dir, err := os.MkdirTemp("", "log-manager-txn-retention-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	c := Config{}
+	c.Segment.MaxStoreBytes = 150
+	c.Retention.MaxAge = 50 * time.Millisecond
+	m, err := NewLogManager(dir, c)
+	require.NoError(t, err)
+	left, err := m.InitProducer()
+	require.NoError(t, err)
+	committing, err := m.InitProducer()
+	require.NoError(t, err)
+
+	// an open transaction's record outlives the retention the manager's topics get, so the transaction's
+	// still aborted when the manager starts again
+	require.NoError(t, m.BeginTxn(left))
+	_, _, err = m.AppendTxn("", nil, &api.Record{Value: []byte("left open"), ProducerId: left}, nil, nil)
+	require.NoError(t, err)
+	for i := uint32(0); i < 5; i++ {
+		require.NoError(t, m.BeginTxn(committing))
+		_, _, err = m.AppendTxn("", nil, &api.Record{Value: []byte("committed"), ProducerId: committing, Sequence: i}, nil, nil)
+		require.NoError(t, err)
+		require.NoError(t, m.CommitTxn(committing))
+	}
+	txns, err := m.Partition(transactionsTopic, 0)
+	require.NoError(t, err)
+	segments := len(txns.loadSegments())
+	require.Greater(t, segments, 1)
+	time.Sleep(60 * time.Millisecond)
+	require.NoError(t, txns.EnforceRetention())
+	require.Len(t, txns.loadSegments(), segments)
+	require.NoError(t, m.Close())
+	m, err = NewLogManager(dir, c)
+	require.NoError(t, err)
+	defer m.Close()
+	l, err := m.Partition("", 0)
+	require.NoError(t, err)
+	highest, err := l.HighestOffset()
+	require.NoError(t, err)
+	lso, err := m.LastStableOffset("", 0)
+	require.NoError(t, err)
+	require.Equal(t, highest+1, lso)
+}
+
+func TestPartitioners(t *testing.T) {
+	rr := &RoundRobin{}
+	var picked []int
+	for i := 0; i < 4; i++ {
+		picked = append(picked, rr.Partition(&api.Record{}, 3))
+	}
+	require.Equal(t, []int{0, 1, 2, 0}, picked)
+
+	hash := KeyHash{Keyless: Explicit(2)}
+	a := hash.Partition(&api.Record{Key: []byte("a")}, 8)
+	require.Equal(t, a, hash.Partition(&api.Record{Key: []byte("a"), Value: []byte("again")}, 8))
+	require.Equal(t, 2, hash.Partition(&api.Record{}, 8))
+	require.Equal(t, 0, KeyHash{}.Partition(&api.Record{}, 8))
+}
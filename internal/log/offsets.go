@@ -0,0 +1,129 @@
+/*
+	Consumer groups commit how far they've read each partition to an internal topic, one record per commit
+	keyed by group, topic and partition, which compaction keeps down to the latest commit for each. The
+	manager reads the topic back into memory when it starts and serves committed offsets from there.
+*/
+
+package log
+
+import (
+	"encoding/binary"
+
+	"github.com/sant470/distlogs/api/v1"
+)
+
+const (
+	// internal topics are the manager's own, hidden from Topics and out of reach of CreateTopic and DeleteTopic
+	internalTopicPrefix = "__"
+	offsetsTopic        = internalTopicPrefix + "offsets"
+)
+
+// retainedTopics are the internal topics whose records are the manager's state: compaction keeps them down
+// to the latest record per key, and retention never deletes them, whatever the manager's config says.
+var retainedTopics = map[string]bool{offsetsTopic: true, transactionsTopic: true}
+
+// loadOffsets reads the committed offsets back into memory, creating the offsets topic if it doesn't exist.
+func (m *LogManager) loadOffsets() error {
+	if _, ok := m.topics[offsetsTopic]; !ok {
+		overrides := Config{}
+		overrides.Compaction.Enabled = true
+		// a commit is on disk before it's acknowledged
+		overrides.Durability.Mode = SyncAlways
+		if err := m.createTopic(offsetsTopic, 1, overrides); err != nil {
+			return err
+		}
+	}
+	l, err := m.Partition(offsetsTopic, 0)
+	if err != nil {
+		return err
+	}
+	m.offsets = make(map[string]uint64)
+	it := l.Iterator(0)
+	for it.Next() {
+		record := it.Record()
+		// a tombstone deletes the commits of a deleted topic
+		if len(record.Value) == 0 {
+			delete(m.offsets, string(record.Key))
+			continue
+		}
+		if len(record.Value) != 8 {
+			continue
+		}
+		m.offsets[string(record.Key)] = enc.Uint64(record.Value)
+	}
+	return it.Err()
+}
+
+// CommitOffset commits off as the next offset the group reads from the topic's partition.
+func (m *LogManager) CommitOffset(group, topic string, partition uint32, off uint64) error {
+	if group == "" {
+		return api.ErrInvalidGroup{Group: group}
+	}
+	if topic == "" {
+		topic = DefaultTopic
+	}
+	l, err := m.Partition(offsetsTopic, 0)
+	if err != nil {
+		return err
+	}
+	key := offsetKey(group, topic, partition)
+	// commits for the same key are appended in the order they're applied in, and none is appended for a
+	// topic once DeleteTopic's deleted its commits
+	m.offsetsMu.Lock()
+	defer m.offsetsMu.Unlock()
+	if _, err = m.Partition(topic, partition); err != nil {
+		return err
+	}
+	if _, err = l.Append(&api.Record{Key: key, Value: enc.AppendUint64(nil, off)}); err != nil {
+		return err
+	}
+	m.offsets[string(key)] = off
+	return nil
+}
+
+// CommittedOffset returns the next offset the group reads from the topic's partition, as it last committed it.
+func (m *LogManager) CommittedOffset(group, topic string, partition uint32) (uint64, error) {
+	if topic == "" {
+		topic = DefaultTopic
+	}
+	m.offsetsMu.Lock()
+	defer m.offsetsMu.Unlock()
+	off, ok := m.offsets[string(offsetKey(group, topic, partition))]
+	if !ok {
+		return 0, api.ErrNoCommittedOffset{Group: group, Topic: topic, Partition: partition}
+	}
+	return off, nil
+}
+
+// deleteOffsets deletes the commits for the topic's partitions from l, the offsets topic's log, with a
+// tombstone for each. It's called with m.offsetsMu held.
+func (m *LogManager) deleteOffsets(l *Log, topic string) error {
+	for key := range m.offsets {
+		if offsetKeyTopic([]byte(key)) != topic {
+			continue
+		}
+		if _, err := l.Append(&api.Record{Key: []byte(key)}); err != nil {
+			return err
+		}
+		delete(m.offsets, key)
+	}
+	return nil
+}
+
+// offsetKeyTopic returns the topic of an offsetKey.
+func offsetKeyTopic(key []byte) string {
+	n, w := binary.Uvarint(key)
+	key = key[w+int(n):]
+	n, w = binary.Uvarint(key)
+	return string(key[w : w+int(n)])
+}
+
+// offsetKey is the key of the group's commits for the topic's partition, the names prefixed with their length.
+func offsetKey(group, topic string, partition uint32) []byte {
+	var b []byte
+	b = binary.AppendUvarint(b, uint64(len(group)))
+	b = append(b, group...)
+	b = binary.AppendUvarint(b, uint64(len(topic)))
+	b = append(b, topic...)
+	return enc.AppendUint32(b, partition)
+}
@@ -0,0 +1,54 @@
+/*
+	A Partitioner picks which of a topic's partitions a record is appended to. Each partition is a log of its
+	own, with its own offsets and its own lock, so spreading a topic's records over partitions spreads its
+	writes. Records only keep their order within a partition: KeyHash sends the records sharing a key to the
+	same one.
+*/
+
+package log
+
+import (
+	"hash/fnv"
+	"sync/atomic"
+
+	"github.com/sant470/distlogs/api/v1"
+)
+
+type Partitioner interface {
+	// Partition returns the partition, out of n, the record goes to.
+	Partition(record *api.Record, n int) int
+}
+
+// RoundRobin spreads records over the partitions in turn.
+type RoundRobin struct {
+	next atomic.Uint64
+}
+
+func (r *RoundRobin) Partition(_ *api.Record, n int) int {
+	return int((r.next.Add(1) - 1) % uint64(n))
+}
+
+// KeyHash sends records to partitions by the FNV-1a hash of their key, and leaves the records without a key
+// to Keyless, or sends them to the first partition if it's nil.
+type KeyHash struct {
+	Keyless Partitioner
+}
+
+func (h KeyHash) Partition(record *api.Record, n int) int {
+	if len(record.Key) == 0 {
+		if h.Keyless == nil {
+			return 0
+		}
+		return h.Keyless.Partition(record, n)
+	}
+	hash := fnv.New32a()
+	hash.Write(record.Key)
+	return int(hash.Sum32() % uint32(n))
+}
+
+// Explicit sends every record to the given partition.
+type Explicit uint32
+
+func (e Explicit) Partition(_ *api.Record, _ int) int {
+	return int(e)
+}
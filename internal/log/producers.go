@@ -0,0 +1,314 @@
+/*
+	Idempotent producers register for an ID and number the records they send to each partition from 0 on.
+	The log remembers the latest runs of sequence numbers each producer appended and the offsets they went
+	to, so a retry of an append that was acknowledged gets its original offset back instead of appending the
+	records again, and an append that skips sequence numbers is rejected. The checks run in the committer,
+	where appends are put in order.
+	The records carry their producer and sequence number, so the table is rebuilt from them when the log is
+	set up. Closing the log writes the table to a snapshot along with the offset it covers, and the log's
+	transactions too, and setup only reads the records past it. So does sealing a segment, once the records
+	the snapshot covers are synced, so setup after a crash only reads the records appended since the latest
+	segment was sealed.
+*/
+
+package log
+
+import (
+	"encoding/json"
+	"os"
+	"path"
+
+	"github.com/sant470/distlogs/api/v1"
+	"go.uber.org/zap"
+)
+
+const (
+	producersTopic       = internalTopicPrefix + "producers"
+	producerSnapshotFile = "producers.json"
+	// maxSequenceRuns is how many runs of sequence numbers are kept per producer, enough for the retries of
+	// a few appends in flight at once
+	maxSequenceRuns = 5
+)
+
+// producerState is what the log remembers of a producer's appends, the latest run last.
+type producerState struct {
+	Runs []sequenceRun
+}
+
+// sequenceRun is a run of consecutive sequence numbers appended at consecutive offsets.
+type sequenceRun struct {
+	Sequence uint32
+	Offset   uint64
+	Count    uint32
+}
+
+// next returns the sequence number expected from the producer next.
+func (p *producerState) next() uint32 {
+	if p == nil || len(p.Runs) == 0 {
+		return 0
+	}
+	last := p.Runs[len(p.Runs)-1]
+	return last.Sequence + last.Count
+}
+
+// offset returns the offset of the record appended with the sequence number, if it's still remembered.
+func (p *producerState) offset(seq uint32) (uint64, bool) {
+	if p == nil {
+		return 0, false
+	}
+	for _, run := range p.Runs {
+		if seq >= run.Sequence && seq-run.Sequence < run.Count {
+			return run.Offset + uint64(seq-run.Sequence), true
+		}
+	}
+	return 0, false
+}
+
+func (p *producerState) add(seq uint32, off uint64, count uint32) {
+	if n := len(p.Runs); n > 0 {
+		last := &p.Runs[n-1]
+		if last.Sequence+last.Count == seq && last.Offset+uint64(last.Count) == off {
+			last.Count += count
+			return
+		}
+	}
+	p.Runs = append(p.Runs, sequenceRun{Sequence: seq, Offset: off, Count: count})
+	if len(p.Runs) > maxSequenceRuns {
+		p.Runs = p.Runs[len(p.Runs)-maxSequenceRuns:]
+	}
+}
+
+func (l *Log) producer(id uint64) *producerState {
+	p, ok := l.producers[id]
+	if !ok {
+		p = &producerState{}
+		l.producers[id] = p
+	}
+	return p
+}
+
+// sequences has the next sequence numbers and the appends of the producers with appends pending in the
+// group being committed.
+type sequences struct {
+	next    map[uint64]uint32
+	appends map[uint64][]*appendRequest
+}
+
+// numbered returns the producer of the append's records, 0 if they don't have one.
+func numbered(req *appendRequest) uint64 {
+	first := req.records[0]
+	// transaction markers aren't numbered
+	if first.Control != api.Control_DATA {
+		return 0
+	}
+	return first.ProducerId
+}
+
+func (l *Log) expectedSequence(id uint64, seqs *sequences) uint32 {
+	if expected, ok := seqs.next[id]; ok {
+		return expected
+	}
+	return l.producers[id].next()
+}
+
+// checkSequence reports whether the append carries on from its producer's sequence numbers. Retries are
+// acknowledged with their original offsets and appends out of sequence fail. It's called with l.mu held.
+func (l *Log) checkSequence(req *appendRequest, seqs *sequences) bool {
+	id := numbered(req)
+	if id == 0 {
+		return true
+	}
+	expected := l.expectedSequence(id, seqs)
+	seq := req.records[0].Sequence
+	if seq == expected {
+		return true
+	}
+	if seq < expected {
+		if original := retried(seqs.appends[id], seq); original != nil {
+			req.original, req.delta = original, uint64(seq-original.records[0].Sequence)
+			return false
+		}
+		if off, ok := l.producers[id].offset(seq); ok {
+			req.off = off
+			// the original may still be waiting on its sync
+			if l.unsynced > 0 {
+				req.batch = l.batch
+			}
+			return false
+		}
+	}
+	req.err = api.ErrOutOfOrderSequence{ProducerID: id, Sequence: seq, Expected: expected}
+	return false
+}
+
+// acceptSequence numbers the records of an append that's going to be appended.
+func (l *Log) acceptSequence(req *appendRequest, seqs *sequences) {
+	id := numbered(req)
+	if id == 0 {
+		return
+	}
+	if seqs.next == nil {
+		seqs.next, seqs.appends = make(map[uint64]uint32), make(map[uint64][]*appendRequest)
+	}
+	seq := req.records[0].Sequence
+	// the batch's records carry on from its first one's sequence number
+	for i, record := range req.records {
+		record.ProducerId, record.Sequence = id, seq+uint32(i)
+	}
+	seqs.next[id] = seq + uint32(len(req.records))
+	seqs.appends[id] = append(seqs.appends[id], req)
+}
+
+// retried returns the append whose records include the sequence number.
+func retried(appends []*appendRequest, seq uint32) *appendRequest {
+	for _, req := range appends {
+		first := req.records[0].Sequence
+		if seq >= first && seq-first < uint32(len(req.records)) {
+			return req
+		}
+	}
+	return nil
+}
+
+// recordSequences adds the appended records' sequence numbers to their producers'. It's called with l.mu held.
+func (l *Log) recordSequences(appended []*appendRequest) {
+	for _, req := range appended {
+		if id := numbered(req); id != 0 {
+			first := req.records[0]
+			l.producer(id).add(first.Sequence, first.Offset, uint32(len(req.records)))
+		}
+	}
+}
+
+type producerSnapshot struct {
+	// Offset is the next offset after the records the snapshot covers
+	Offset      uint64
+	Producers   map[uint64]*producerState
+	OpenTxns    map[uint64]uint64
+	AbortedTxns []abortedTxn
+}
+
+// loadProducers rebuilds the producers' table and the log's transactions from the snapshot and the records
+// past it.
+func (l *Log) loadProducers() error {
+	l.producers, l.openTxns, l.aborted = make(map[uint64]*producerState), make(map[uint64]uint64), nil
+	segments := l.loadSegments()
+	next := segments[len(segments)-1].nextOffset.Load()
+	var from uint64
+	b, err := os.ReadFile(path.Join(l.Dir, producerSnapshotFile))
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	// a snapshot that doesn't decode, or covers records the log has lost since, is of no use
+	var snapshot producerSnapshot
+	if err == nil && json.Unmarshal(b, &snapshot) == nil && snapshot.Offset <= next &&
+		snapshot.Producers != nil && snapshot.OpenTxns != nil {
+		l.producers, l.openTxns, l.aborted = snapshot.Producers, snapshot.OpenTxns, snapshot.AbortedTxns
+		from = snapshot.Offset
+	}
+	it := l.Iterator(from)
+	for it.Next() {
+		record := it.Record()
+		if record.ProducerId != 0 && record.Control == api.Control_DATA {
+			l.producer(record.ProducerId).add(record.Sequence, record.Offset, 1)
+		}
+		l.observeTxn(record)
+	}
+	lowest, _ := l.LowestOffset()
+	l.pruneAbortedTxns(lowest)
+	l.publishTxns()
+	// the log is still usable, its producers may only find their retries appended again
+	if err = it.Err(); err != nil {
+		l.logger.Warn(
+			"failed to rebuild producer state",
+			zap.String("dir", l.Dir),
+			zap.Uint64("offset", it.Offset()),
+			zap.Error(err),
+		)
+	}
+	return nil
+}
+
+// snapshotProducers writes the producers' table and the log's transactions to the snapshot. It's called with
+// l.mu held, by the committer or once it's stopped.
+func (l *Log) snapshotProducers() error {
+	segments := l.loadSegments()
+	l.pruneAbortedTxns(segments[0].baseOffset)
+	b, err := json.Marshal(producerSnapshot{
+		Offset:      segments[len(segments)-1].nextOffset.Load(),
+		Producers:   l.producers,
+		OpenTxns:    l.openTxns,
+		AbortedTxns: l.aborted,
+	})
+	if err != nil {
+		return err
+	}
+	// the snapshot's synced before it replaces the last one, a crash leaves either of them whole
+	tmp := path.Join(l.Dir, producerSnapshotFile+".tmp")
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(b)
+	if err == nil {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp, path.Join(l.Dir, producerSnapshotFile))
+}
+
+// checkpointProducers snapshots the producers' table once the sealed segment's been sealed, see
+// snapshotProducers. It's called with l.mu held, by the committer once it's recorded the appended records.
+func (l *Log) checkpointProducers(sealed *segment) {
+	// there's nothing setup would have to read records for
+	if len(l.producers) == 0 && len(l.openTxns) == 0 {
+		return
+	}
+	// the snapshot can't cover records a crash may still lose, the ones the commit appended past the roll
+	// included
+	err := sealed.Sync()
+	if s := l.activeSegment; err == nil && s.entries.Load() > 0 {
+		err = s.Sync()
+	}
+	if err == nil {
+		err = l.snapshotProducers()
+	}
+	// setup reads more records, that's all
+	if err != nil {
+		l.logger.Warn(
+			"failed to snapshot producer state",
+			zap.String("dir", l.Dir),
+			zap.Error(err),
+		)
+	}
+}
+
+// initProducers creates the topic producer IDs are handed out from if it doesn't exist.
+func (m *LogManager) initProducers() error {
+	if _, ok := m.topics[producersTopic]; ok {
+		return nil
+	}
+	// an ID handed out is on disk before the producer gets it, so it's never handed out again
+	overrides := Config{}
+	overrides.Durability.Mode = SyncAlways
+	return m.createTopic(producersTopic, 1, overrides)
+}
+
+// InitProducer registers an idempotent producer and returns its ID. Every registration appends a record
+// to an internal topic and gets the ID that follows its offset, so IDs are never 0 nor handed out twice.
+func (m *LogManager) InitProducer() (uint64, error) {
+	l, err := m.Partition(producersTopic, 0)
+	if err != nil {
+		return 0, err
+	}
+	off, err := l.Append(&api.Record{})
+	if err != nil {
+		return 0, err
+	}
+	return off + 1, nil
+}
@@ -0,0 +1,251 @@
+/*
+	A segment that wasn't closed cleanly can't be trusted as is. The store may end with a frame that
+	was only partly written, and the index still has the room it was mapped with, so its tail is
+	either zeroed or holds entries for frames that never made it to the store. The index may also be
+	missing altogether, or shorter than the store after a restore from backup.
+	Recovery walks the index back from its tail until it finds an entry pointing at a complete frame,
+	indexes whatever complete records the store holds past that entry, then cuts both files back to
+	the last consistent record. A sparse index only gets the entries its interval calls for.
+	Only a torn tail is cut off: a frame that doesn't check out but has intact frames after it is corruption
+	rather than a crash, and recovery fails with api.ErrCorruptRecord instead of dropping the records after it.
+	A batch in a codec that isn't registered fails recovery with api.ErrUnknownCodec, leaving the files as
+	they are, for the log to be opened again once the codec's registered.
+	The time index is reconciled last: entries for records that didn't survive are dropped, and the records
+	past its last entry are scanned to re-add any missing entries and find the segment's latest timestamp.
+*/
+
+package log
+
+import (
+	"io"
+	"slices"
+	"sort"
+
+	"github.com/sant470/distlogs/api/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+// Repair describes what recovery had to fix in a segment to get it back to a consistent state.
+type Repair struct {
+	BaseOffset uint64
+	// DroppedIndexEntries counts index entries that were written but point at a missing or torn frame.
+	DroppedIndexEntries uint64
+	// TrimmedIndexBytes counts the index bytes past the last consistent entry, zeroed preallocation included.
+	TrimmedIndexBytes uint64
+	// RebuiltIndexEntries counts the entries rebuilt from records the store had but the index didn't.
+	RebuiltIndexEntries uint64
+	// TruncatedStoreBytes counts the store bytes past the end of the last consistent record.
+	TruncatedStoreBytes uint64
+}
+
+// Repaired reports whether recovery changed anything on disk.
+func (r Repair) Repaired() bool {
+	return r.DroppedIndexEntries > 0 || r.TrimmedIndexBytes > 0 ||
+		r.RebuiltIndexEntries > 0 || r.TruncatedStoreBytes > 0
+}
+
+func (s *segment) recover() (Repair, error) {
+	r := Repair{BaseOffset: s.baseOffset}
+	if err := s.store.Flush(); err != nil {
+		return r, err
+	}
+	s.batch.Store(nil)
+	s.unindexedBytes, s.unindexedRecords = 0, 0
+	indexSize := s.index.size
+	n := min(indexSize, uint64(len(s.index.mapped()))) / endWidth
+	// next is the relative offset past the last consistent record, rest holds the records that come after
+	// the last consistent entry in its frame, at framePos
+	var (
+		end, next, framePos uint64
+		rest                []*api.Record
+	)
+	for ; n > 0; n-- {
+		// offsets increase from one entry to the next, with gaps once the segment's been compacted,
+		// so a zeroed entry is never at or past its place in the index
+		off, pos := s.index.entry(n - 1)
+		if uint64(off) >= n-1 {
+			records, width, err := s.readRecords(pos)
+			if _, ok := err.(api.ErrUnknownCodec); ok {
+				return r, err
+			}
+			i := slices.IndexFunc(records, func(record *api.Record) bool {
+				return record.Offset == s.baseOffset+uint64(off)
+			})
+			if err == nil && i >= 0 {
+				framePos = pos
+				end, next = pos+width, records[len(records)-1].Offset-s.baseOffset+1
+				// a sparse index only has the frame's first record, the interval counts from there
+				if s.sparse() {
+					s.unindexedBytes, s.unindexedRecords = width, uint64(len(records))
+				} else {
+					rest = records[i+1:]
+				}
+				break
+			}
+		}
+		if off != 0 || pos != 0 {
+			r.DroppedIndexEntries++
+		}
+	}
+	s.index.Truncate(n)
+	// the entries for the rest of a batch may not have made it
+	for _, record := range rest {
+		if err := s.index.Write(uint32(record.Offset-s.baseOffset), framePos); err != nil {
+			return r, err
+		}
+		r.RebuiltIndexEntries++
+		n++
+	}
+	// index the complete records the store has past the last indexed one
+	for {
+		records, width, err := s.readRecords(end)
+		if _, ok := err.(api.ErrUnknownCodec); ok {
+			return r, err
+		}
+		if err == nil && records[0].Offset < s.baseOffset+next {
+			// a frame going back on the offsets, like a zeroed one, is no more to be trusted
+			err = api.ErrCorruptRecord{Pos: end}
+		}
+		if err != nil {
+			if err != io.EOF && s.intactAfter(end, width, next) {
+				return r, api.ErrCorruptRecord{Offset: s.baseOffset + next, Pos: end}
+			}
+			break
+		}
+		indexed := s.indexFrame(width, len(records))
+		for k, record := range records {
+			if !indexed || (k > 0 && s.sparse()) {
+				continue
+			}
+			if err = s.index.Write(uint32(record.Offset-s.baseOffset), end); err != nil {
+				return r, err
+			}
+			r.RebuiltIndexEntries++
+			n++
+		}
+		end += width
+		next = records[len(records)-1].Offset - s.baseOffset + 1
+	}
+	if indexSize > s.index.size {
+		r.TrimmedIndexBytes = indexSize - s.index.size
+	}
+	if s.store.size > end {
+		r.TruncatedStoreBytes = s.store.size - end
+		if err := s.store.Truncate(end); err != nil {
+			return r, err
+		}
+	}
+	s.entries.Store(n)
+	s.nextOffset.Store(s.baseOffset + next)
+	return r, s.recoverTimeIndex(next)
+}
+
+// readRecords reads and decodes the records in the frame at pos, and returns the frame's width, which is
+// also known for the frames that fail their checksum or don't decode.
+func (s *segment) readRecords(pos uint64) ([]*api.Record, uint64, error) {
+	f, err := s.store.readFrame(pos, s.store.size)
+	if err != nil {
+		return nil, f.width, err
+	}
+	ps := [][]byte{f.payload}
+	if f.version == frameBatch {
+		if ps, err = decodeBatch(f.payload); err != nil {
+			return nil, f.width, err
+		}
+		if len(ps) == 0 {
+			return nil, f.width, api.ErrCorruptRecord{Pos: pos}
+		}
+	}
+	records := make([]*api.Record, len(ps))
+	for i, p := range ps {
+		records[i] = &api.Record{}
+		if err = proto.Unmarshal(p, records[i]); err != nil {
+			return nil, f.width, err
+		}
+	}
+	return records, f.width, nil
+}
+
+// intactAfter reports whether an intact frame, with records past next, the relative offset past the last
+// consistent record, follows the bad frame of the given width at pos. A torn tail only has more of what the
+// crash left behind after it.
+func (s *segment) intactAfter(pos, width, next uint64) bool {
+	for width > 0 {
+		pos += width
+		records, w, err := s.readRecords(pos)
+		if err == nil && records[0].Offset >= s.baseOffset+next {
+			return true
+		}
+		// the batch checked out, only its codec's missing
+		if _, ok := err.(api.ErrUnknownCodec); ok {
+			return true
+		}
+		width = w
+	}
+	return false
+}
+
+// recoverTimeIndex drops the time index entries for records past next, the relative offset past the
+// last consistent record, and indexes the records past the last entry left.
+func (s *segment) recoverTimeIndex(next uint64) error {
+	entries := s.timeIndex.entries
+	valid := len(entries)
+	for valid > 0 && uint64(entries[valid-1].off) >= next {
+		valid--
+	}
+	// also cuts off a partly written entry
+	if err := s.timeIndex.Truncate(valid); err != nil {
+		return err
+	}
+	// the index may be sparse, so the records are scanned frame by frame from the last entry's
+	var (
+		start uint32
+		pos   uint64
+	)
+	if last, ok := s.timeIndex.Last(); ok {
+		start = last.off
+		if n := s.entries.Load(); n > 0 {
+			_, pos = s.index.entry(s.floor(start, n))
+		}
+	}
+	for pos < s.store.size {
+		records, width, err := s.readRecords(pos)
+		if _, ok := err.(api.ErrUnknownCodec); ok {
+			return err
+		}
+		if err != nil {
+			// a corrupt record is reported when it's read, it just goes without a time index entry,
+			// and the scan carries on from the next indexed frame
+			n := s.entries.Load()
+			i := sort.Search(int(n), func(i int) bool {
+				_, next := s.index.entry(uint64(i))
+				return next > pos
+			})
+			if uint64(i) == n {
+				break
+			}
+			_, pos = s.index.entry(uint64(i))
+			continue
+		}
+		for k, record := range records {
+			off := uint32(record.Offset - s.baseOffset)
+			if off < start {
+				continue
+			}
+			// like appends do, a frame's width is counted against its first record
+			var w uint64
+			if k == 0 {
+				w = width
+			}
+			// the record the last entry points at is already indexed, Observe only counts it
+			if err := s.timeIndex.Observe(
+				record.Timestamp, off, w, s.config.Segment.TimeIndexIntervalBytes,
+			); err != nil {
+				return err
+			}
+			s.maxTimestamp.Store(max(s.maxTimestamp.Load(), record.Timestamp))
+		}
+		pos += width
+	}
+	return nil
+}
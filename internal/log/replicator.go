@@ -3,20 +3,31 @@ package log
 import (
 	"context"
 	"sync"
+	"time"
 
 	"github.com/sant470/distlogs/api/v1"
 	"go.uber.org/zap"
 	"google.golang.org/grpc"
+	"google.golang.org/protobuf/proto"
 )
 
+// defaultRescanInterval is how often the topics to replicate are listed again if RescanInterval is left zero.
+const defaultRescanInterval = 10 * time.Second
+
 type Replicator struct {
 	DialOptions []grpc.DialOption
 	LocalServer api.LogClient
-	logger      *zap.Logger
-	mu          sync.Mutex
-	servers     map[string]chan struct{}
-	closed      bool
-	close       chan struct{}
+	// Topics and Partitions list the topics to replicate and how many partitions each has, as LogManager's
+	// methods do, only the default topic's single partition if they're nil. Every server has to have the
+	// same partitions. The topics are listed again every RescanInterval, for the ones created since.
+	Topics         func() []string
+	Partitions     func(topic string) (int, error)
+	RescanInterval time.Duration
+	logger         *zap.Logger
+	mu             sync.Mutex
+	servers        map[string]chan struct{}
+	closed         bool
+	close          chan struct{}
 }
 
 func (r *Replicator) init() {
@@ -73,7 +84,8 @@ func (r *Replicator) Leave(name string) error {
 	return nil
 }
 
-// replicate continuously copies log records from the remote server
+// replicate continuously copies the records of every topic's partitions from the remote server, the
+// topics created since included.
 func (r *Replicator) replicate(addr string, stopCh chan struct{}) {
 	conn, err := grpc.Dial(addr, r.DialOptions...)
 	if err != nil {
@@ -84,38 +96,123 @@ func (r *Replicator) replicate(addr string, stopCh chan struct{}) {
 
 	client := api.NewLogClient(conn)
 
-	ctx := context.Background()
-	stream, err := client.ConsumeStream(ctx, &api.ConsumeRequest{Offset: 0})
-	if err != nil {
-		r.logError(err, "failed to consume", addr)
-		return
+	// cancelling the streams on the way out also ends the remote server's wait for new records
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	interval := r.RescanInterval
+	if interval == 0 {
+		interval = defaultRescanInterval
 	}
-	records := make(chan *api.Record)
-	go func() {
-		for {
-			recv, err := stream.Recv()
-			if err != nil {
-				r.logError(err, "failed to receive", addr)
-				return
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	// a partition whose replication stopped, say because the remote server didn't have it yet, carries on
+	// from where it stopped on the next scan
+	replications := make(map[topicPartition]*replication)
+	for {
+		for _, p := range r.partitions(addr) {
+			rep, ok := replications[p]
+			if ok {
+				select {
+				case <-rep.done:
+				default:
+					continue
+				}
+			} else {
+				rep = &replication{}
+				replications[p] = rep
 			}
-			records <- recv.Record
+			rep.done = make(chan struct{})
+			go func() {
+				defer close(rep.done)
+				rep.next = r.replicatePartition(ctx, client, addr, p, rep.next)
+			}()
 		}
-	}()
-	for {
 		select {
 		case <-r.close:
 			return
 		case <-stopCh:
 			return
-		case record := <-records:
-			_, err := r.LocalServer.Produce(ctx, &api.ProduceRequest{Record: record})
-			if err != nil {
-				r.logError(err, "failed to produce", addr)
-				return
+		case <-ticker.C:
+		}
+	}
+}
+
+type topicPartition struct {
+	topic     string
+	partition uint32
+}
+
+// replication is a partition's replication from a remote server: next is the offset it carries on from,
+// read once done's closed.
+type replication struct {
+	done chan struct{}
+	next uint64
+}
+
+// partitions lists the partitions of the topics to replicate.
+func (r *Replicator) partitions(addr string) []topicPartition {
+	topics := []string{DefaultTopic}
+	if r.Topics != nil {
+		topics = r.Topics()
+	}
+	var partitions []topicPartition
+	for _, topic := range topics {
+		n := 1
+		if r.Partitions != nil {
+			var err error
+			if n, err = r.Partitions(topic); err != nil {
+				// the topic's been deleted since it was listed
+				r.logError(err, "failed to list partitions", addr)
+				continue
 			}
 		}
+		for i := 0; i < n; i++ {
+			partitions = append(partitions, topicPartition{topic: topic, partition: uint32(i)})
+		}
 	}
+	return partitions
+}
 
+// replicatePartition copies the records of the partition from the remote server to the same partition of
+// the local one, from the offset on, until the context's done or it fails. It returns the offset to carry
+// on from.
+func (r *Replicator) replicatePartition(
+	ctx context.Context, client api.LogClient, addr string, p topicPartition, from uint64,
+) uint64 {
+	// the records are produced again as plain data, so transaction markers and the records of aborted or
+	// open transactions aren't copied, only the ones committed
+	stream, err := client.ConsumeStream(ctx, &api.ConsumeRequest{
+		Offset:    proto.Uint64(from),
+		Topic:     p.topic,
+		Partition: p.partition,
+		Isolation: api.Isolation_READ_COMMITTED,
+	})
+	if err != nil {
+		r.logError(err, "failed to consume", addr)
+		return from
+	}
+	for {
+		recv, err := stream.Recv()
+		if err != nil {
+			if ctx.Err() == nil {
+				r.logError(err, "failed to receive", addr)
+			}
+			return from
+		}
+		next := recv.Record.Offset + 1
+		_, err = r.LocalServer.Produce(ctx, &api.ProduceRequest{
+			Record:    recv.Record,
+			Topic:     p.topic,
+			Partition: proto.Uint32(p.partition),
+		})
+		if err != nil {
+			if ctx.Err() == nil {
+				r.logError(err, "failed to produce", addr)
+			}
+			return from
+		}
+		from = next
+	}
 }
 
 // Close stops all replication
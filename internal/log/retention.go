@@ -0,0 +1,182 @@
+/*
+	Retention deletes the oldest sealed segments once their newest record is older than Retention.MaxAge,
+	or while the log's stores add up to more than Retention.MaxBytes. Offsets stay contiguous, so it stops
+	at the first segment it has to keep, and the active segment is never deleted.
+	Reads don't lock, so deleting a segment only takes it off the published list. Every reader takes a
+	reference to the segment it reads, and the segment's files are closed and removed once the log and
+	the last reader have let go of it.
+*/
+
+package log
+
+import (
+	"os"
+	"slices"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// acquire takes a reference to the segment for reading it. It fails once the segment's been deleted, or
+// while it's fenced.
+func (s *segment) acquire() bool {
+	for {
+		refs := s.refs.Load()
+		if refs == 0 {
+			return false
+		}
+		if s.refs.CompareAndSwap(refs, refs+1) {
+			break
+		}
+	}
+	// the log's reference keeps this from dropping the last one
+	if s.fenced.Load() {
+		s.refs.Add(-1)
+		return false
+	}
+	return true
+}
+
+// fence waits for the segment's readers to be done and keeps new ones from acquiring it until unfence, for
+// its index to be rewritten or unmapped. It's called with l.mu held, so the segment can't be released
+// meanwhile, and with l.rebuildMu held or the log closed, for the readers kept out to wait or give up.
+func (s *segment) fence() {
+	s.fenced.Store(true)
+	for s.refs.Load() > 1 {
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func (s *segment) unfence() {
+	s.fenced.Store(false)
+}
+
+// waitRebuild waits for RebuildIndexes to be done with the segment a reader couldn't acquire, if that's
+// why it couldn't, and reports whether the reader can try again: it can't once the log's closed.
+func (l *Log) waitRebuild() bool {
+	l.rebuildMu.RLock()
+	defer l.rebuildMu.RUnlock()
+	select {
+	case <-l.stopCommit:
+		return false
+	default:
+		return true
+	}
+}
+
+// release drops a reference to the segment and removes it if that was the last one, or only closes it if
+// compaction replaced its files.
+func (s *segment) release() error {
+	if s.refs.Add(-1) != 0 {
+		return nil
+	}
+	if s.replaced.Load() {
+		return s.Close()
+	}
+	return s.Remove()
+}
+
+// lastAppended returns when the segment's newest record was appended. Segments written before the log
+// stamped records go by their store's modification time.
+func (s *segment) lastAppended() (time.Time, error) {
+	if ts := s.maxTimestamp.Load(); ts != 0 {
+		return time.Unix(0, ts), nil
+	}
+	fi, err := os.Stat(s.store.Name())
+	if err != nil {
+		return time.Time{}, err
+	}
+	return fi.ModTime(), nil
+}
+
+// releaseSegment releases a segment a reader is done with.
+func (l *Log) releaseSegment(s *segment) {
+	if err := s.release(); err != nil {
+		l.logger.Error(
+			"failed to remove segment",
+			zap.String("dir", l.Dir),
+			zap.Uint64("base_offset", s.baseOffset),
+			zap.Error(err),
+		)
+	}
+}
+
+// EnforceRetention deletes the oldest sealed segments the retention policy no longer keeps.
+func (l *Log) EnforceRetention() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	r := l.Config.Retention
+	segments := l.loadSegments()
+	var total uint64
+	for _, s := range segments {
+		total += s.store.size
+	}
+	now := time.Now()
+	// keeping at least one segment keeps the active one
+	var n int
+	for ; n < len(segments)-max(r.MinSegments, 1); n++ {
+		s := segments[n]
+		last, err := s.lastAppended()
+		if err != nil {
+			return err
+		}
+		expired := r.MaxAge > 0 && now.Sub(last) > r.MaxAge
+		oversized := r.MaxBytes > 0 && total > r.MaxBytes
+		if !expired && !oversized {
+			break
+		}
+		total -= s.store.size
+	}
+	if n == 0 {
+		return nil
+	}
+	l.publish(slices.Clone(segments[n:]))
+	if l.pruneAbortedTxns(segments[n].baseOffset) {
+		l.publishTxns()
+	}
+	var err error
+	for _, s := range segments[:n] {
+		l.logger.Info(
+			"deleted segment",
+			zap.String("dir", l.Dir),
+			zap.Uint64("base_offset", s.baseOffset),
+			zap.Uint64("next_offset", s.nextOffset.Load()),
+		)
+		if rerr := s.release(); rerr != nil && err == nil {
+			err = rerr
+		}
+	}
+	return err
+}
+
+func (l *Log) startRetention() {
+	if l.Config.Retention.MaxAge == 0 && l.Config.Retention.MaxBytes == 0 {
+		return
+	}
+	l.stopRetain = make(chan struct{})
+	l.retainDone = make(chan struct{})
+	go func() {
+		defer close(l.retainDone)
+		ticker := time.NewTicker(l.Config.Retention.CheckInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-l.stopRetain:
+				return
+			case <-ticker.C:
+				if err := l.EnforceRetention(); err != nil {
+					l.logger.Error("failed to enforce retention", zap.String("dir", l.Dir), zap.Error(err))
+				}
+			}
+		}
+	}()
+}
+
+func (l *Log) stopRetention() {
+	if l.stopRetain == nil {
+		return
+	}
+	close(l.stopRetain)
+	<-l.retainDone
+	l.stopRetain = nil
+}
@@ -8,19 +8,45 @@
 package log
 
 import (
+	"cmp"
 	"fmt"
+	"io"
 	"os"
 	"path"
+	"slices"
+	"sort"
+	"sync/atomic"
+	"time"
 
 	"github.com/sant470/distlogs/api/v1"
 	"google.golang.org/protobuf/proto"
 )
 
 type segment struct {
-	store                  *store
-	index                  *index
-	baseOffset, nextOffset uint64
-	config                 Config
+	store      *store
+	index      *index
+	baseOffset uint64
+	// nextOffset is only moved past a record once its frame and index entry are written,
+	// so readers can go by it without locking
+	nextOffset atomic.Uint64
+	// entries is how many index entries readers can use, published before nextOffset
+	entries   atomic.Uint64
+	timeIndex *timeIndex
+	// maxTimestamp is the latest timestamp in the segment, appends never stamp a record with an earlier one
+	maxTimestamp atomic.Int64
+	// refs counts the log's reference to the segment and the readers', see retention.go
+	refs atomic.Int64
+	// replaced is set once compaction has swapped the segment's files for compacted ones
+	replaced atomic.Bool
+	// fenced keeps readers out while RebuildIndexes rewrites the index
+	fenced atomic.Bool
+	batch  atomic.Pointer[decodedBatch]
+	// unindexedBytes and unindexedRecords count what went to the store since the last index entry,
+	// for a sparse index
+	unindexedBytes   uint64
+	unindexedRecords uint64
+	config           Config
+	repair           Repair
 }
 
 func newSegment(dir string, baseOffset uint64, c Config) (*segment, error) {
@@ -28,6 +54,7 @@ func newSegment(dir string, baseOffset uint64, c Config) (*segment, error) {
 		baseOffset: baseOffset,
 		config:     c,
 	}
+	s.refs.Store(1)
 	storeFile, err := os.OpenFile(
 		path.Join(dir, fmt.Sprintf("%d%s", baseOffset, ".store")),
 		os.O_RDWR|os.O_CREATE|os.O_APPEND,
@@ -50,60 +77,324 @@ func newSegment(dir string, baseOffset uint64, c Config) (*segment, error) {
 	if s.index, err = newIndex(indexFile, c); err != nil {
 		return nil, err
 	}
-	if off, _, err := s.index.Read(-1); err != nil {
-		s.nextOffset = baseOffset
-	} else {
-		s.nextOffset = baseOffset + uint64(off) + 1
+	timeIndexFile, err := os.OpenFile(
+		path.Join(dir, fmt.Sprintf("%d%s", baseOffset, ".timeindex")),
+		os.O_RDWR|os.O_CREATE|os.O_APPEND,
+		0644,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if s.timeIndex, err = newTimeIndex(timeIndexFile); err != nil {
+		return nil, err
+	}
+	// recover also works out the segment's next offset from what's on disk
+	if s.repair, err = s.recover(); err != nil {
+		// the recovery error is the one worth reporting, the files are left as recovery found them
+		_ = s.Close()
+		return nil, err
 	}
 	return s, nil
 }
 
 func (s *segment) Append(record *api.Record) (offset uint64, err error) {
-	cur := s.nextOffset
-	record.Offset = cur
-	p, err := proto.Marshal(record)
-	if err != nil {
+	return s.AppendBatch([]*api.Record{record})
+}
+
+// AppendBatch appends the records atomically and returns the first one's offset.
+func (s *segment) AppendBatch(records []*api.Record) (first uint64, err error) {
+	if _, err = s.appendGroup([][]*api.Record{records}); err != nil {
 		return 0, err
 	}
-	_, pos, err := s.store.Append(p)
-	if err != nil {
-		return 0, err
+	return records[0].Offset, nil
+}
+
+// appendGroup appends the first batch and as many of the rest as the segment has room for with a single
+// store write, and returns how many it appended, stamping their records with their offsets and append time.
+func (s *segment) appendGroup(batches [][]*api.Record) (int, error) {
+	var (
+		records []*api.Record
+		ps      [][]byte
+		sizes   []int
+		n       int
+	)
+	storeSize := s.store.size
+	next := s.nextOffset.Load()
+	// keep timestamps from going backwards within the segment if the clock does
+	ts := max(time.Now().UnixNano(), s.maxTimestamp.Load())
+	for ; n < len(batches); n++ {
+		batch := batches[n]
+		if n > 0 && storeSize > s.config.Segment.MaxStoreBytes {
+			break
+		}
+		for _, record := range batch {
+			record.Offset = next + uint64(len(ps))
+			record.Timestamp = ts
+			p, err := proto.Marshal(record)
+			if err != nil {
+				return 0, err
+			}
+			records, ps = append(records, record), append(ps, p)
+			storeSize += uint64(lenWidth + crcWidth + len(p))
+		}
+		sizes = append(sizes, len(batch))
 	}
-	// index offset are relative to base offset
-	if err = s.index.Write(
-		uint32(s.nextOffset-uint64(s.baseOffset)),
-		pos,
-	); err != nil {
+	if len(ps) == 0 {
+		return n, nil
+	}
+	if err := s.write(records, ps, sizes); err != nil {
 		return 0, err
 	}
-	s.nextOffset++
-	return cur, nil
+	return n, nil
+}
+
+// write appends records that already have their offsets and timestamps, given marshalled, with a single
+// store write. sizes splits the records into batches, each written as a single frame, or a frame per record
+// if nil; when the log compresses, all the records go in a single frame, unless they're smaller than
+// MinBatchBytes. They all become visible to readers at once.
+func (s *segment) write(records []*api.Record, ps [][]byte, sizes []int) error {
+	c := s.config.Compression.Codec
+	if c != nil {
+		var n uint64
+		for _, p := range ps {
+			n += uint64(len(p))
+		}
+		if n < s.config.Compression.MinBatchBytes {
+			c = nil
+		}
+	}
+	if c != nil {
+		sizes = []int{len(ps)}
+	} else if sizes == nil {
+		sizes = slices.Repeat([]int{1}, len(ps))
+	}
+	var frames []frame
+	for start, size := 0, 0; start < len(ps); start += size {
+		size = sizes[len(frames)]
+		if c == nil && size == 1 {
+			frames = append(frames, frame{version: frameCRC, payload: ps[start]})
+			continue
+		}
+		batch, err := encodeBatch(cmp.Or(c, uncompressed), ps[start:start+size])
+		if err != nil {
+			return err
+		}
+		frames = append(frames, frame{version: frameBatch, payload: batch})
+	}
+	_, positions, err := s.store.AppendFrames(frames)
+	if err != nil {
+		return err
+	}
+	i := 0
+	for j, size := range sizes {
+		width := uint64(lenWidth + crcWidth + len(frames[j].payload))
+		indexed := s.indexFrame(width, size)
+		for k := 0; k < size; k, i = k+1, i+1 {
+			record := records[i]
+			// index offset are relative to base offset
+			rel := uint32(record.Offset - s.baseOffset)
+			if indexed && (k == 0 || !s.sparse()) {
+				if err := s.index.Write(rel, positions[j]); err != nil {
+					return err
+				}
+				s.entries.Add(1)
+			}
+			// the time index goes by the store bytes written, which are all a frame's first record's
+			if k > 0 {
+				width = 0
+			}
+			if err := s.timeIndex.Observe(
+				record.Timestamp, rel, width, s.config.Segment.TimeIndexIntervalBytes,
+			); err != nil {
+				return err
+			}
+			s.maxTimestamp.Store(max(s.maxTimestamp.Load(), record.Timestamp))
+		}
+	}
+	s.nextOffset.Store(records[len(records)-1].Offset + 1)
+	return nil
+}
+
+// sparse reports whether the index only has entries for some frames' first records.
+func (s *segment) sparse() bool {
+	return s.config.Segment.IndexIntervalBytes > 0 || s.config.Segment.IndexIntervalRecords > 0
+}
+
+// indexFrame reports whether the frame about to be written, width bytes wide and holding records, gets
+// index entries. Every frame does in a dense index, in a sparse one only the first frame does and then one
+// once an interval has gone by since the last entry.
+func (s *segment) indexFrame(width uint64, records int) bool {
+	if !s.sparse() {
+		return true
+	}
+	c := s.config.Segment
+	due := s.index.size == 0 ||
+		(c.IndexIntervalBytes > 0 && s.unindexedBytes >= c.IndexIntervalBytes) ||
+		(c.IndexIntervalRecords > 0 && s.unindexedRecords >= c.IndexIntervalRecords)
+	if due {
+		s.unindexedBytes, s.unindexedRecords = 0, 0
+	}
+	s.unindexedBytes += width
+	s.unindexedRecords += uint64(records)
+	return due
 }
 
+// Read returns the record at off, or the next one after it if compaction removed it. It returns io.EOF
+// if there's none left in the segment.
 func (s *segment) Read(off uint64) (*api.Record, error) {
-	_, pos, err := s.index.Read(int64(off - s.baseOffset))
+	next := s.nextOffset.Load()
+	if off < s.baseOffset || next <= off {
+		return nil, io.EOF
+	}
+	// the entries are in place since nextOffset is past them, so there's no need to go through the index's size
+	n := s.entries.Load()
+	rel := uint32(off - s.baseOffset)
+	// a dense index has the record's entry in its place unless the segment's been compacted, otherwise
+	// the store is scanned from the last entry before it
+	i := uint64(rel)
+	if i >= n {
+		i = s.floor(rel, n)
+	} else if out, _ := s.index.entry(i); out != rel {
+		i = s.floor(rel, n)
+	}
+	_, pos := s.index.entry(i)
+	// the batch's records are indexed in order, so the entries before i pointing at it are skipped
+	skip := 0
+	for ; i > 0; i-- {
+		if _, prev := s.index.entry(i - 1); prev != pos {
+			break
+		}
+		skip++
+	}
+	for {
+		ps, width, err := s.readPayloads(pos)
+		if err != nil {
+			if corrupt, ok := err.(api.ErrCorruptRecord); ok {
+				corrupt.Offset = off
+				return nil, corrupt
+			}
+			return nil, err
+		}
+		if skip >= len(ps) {
+			return nil, api.ErrCorruptRecord{Offset: off, Pos: pos}
+		}
+		for _, p := range ps[skip:] {
+			record := &api.Record{}
+			// legacy frames carry no checksum, so a payload that doesn't decode is our only sign of corruption
+			if err = proto.Unmarshal(p, record); err != nil {
+				return nil, api.ErrCorruptRecord{Offset: off, Pos: pos}
+			}
+			// frames past nextOffset may still be getting their index entries
+			if record.Offset >= next {
+				return nil, io.EOF
+			}
+			if record.Offset >= off {
+				return record, nil
+			}
+		}
+		pos, skip = pos+width, 0
+	}
+}
+
+// decodedBatch is the last batch a reader decompressed, kept for the next reads from the same batch.
+type decodedBatch struct {
+	pos   uint64
+	width uint64
+	ps    [][]byte
+}
+
+// readPayloads returns the marshalled records in the frame at pos, and the frame's width.
+func (s *segment) readPayloads(pos uint64) ([][]byte, uint64, error) {
+	if batch := s.batch.Load(); batch != nil && batch.pos == pos {
+		return batch.ps, batch.width, nil
+	}
+	f, err := s.store.ReadFrame(pos)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
+	}
+	if f.version != frameBatch {
+		return [][]byte{f.payload}, f.width, nil
+	}
+	ps, err := decodeBatch(f.payload)
+	// a batch in a codec that isn't registered is intact, it just can't be read here
+	if _, ok := err.(api.ErrUnknownCodec); ok {
+		return nil, 0, err
 	}
-	p, err := s.store.Read(pos)
 	if err != nil {
-		return nil, err
+		return nil, 0, api.ErrCorruptRecord{Pos: pos}
 	}
-	record := &api.Record{}
-	err = proto.Unmarshal(p, record)
+	s.batch.Store(&decodedBatch{pos: pos, width: f.width, ps: ps})
+	return ps, f.width, nil
+}
+
+// floor returns the last of the first n index entries at or before rel, or the first entry if there's none.
+func (s *segment) floor(rel uint32, n uint64) uint64 {
+	i := sort.Search(int(n), func(i int) bool {
+		out, _ := s.index.entry(uint64(i))
+		return out > rel
+	})
+	return uint64(max(i-1, 0))
+}
+
+// offsetForTime returns the first offset in the segment appended at or after ts, or the segment's next
+// offset if there's none.
+func (s *segment) offsetForTime(ts int64) (uint64, error) {
+	next := s.nextOffset.Load()
+	for off := s.baseOffset + uint64(s.timeIndex.Lookup(ts)); off < next; {
+		record, err := s.Read(off)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return 0, err
+		}
+		if record.Timestamp >= ts {
+			return record.Offset, nil
+		}
+		off = record.Offset + 1
+	}
+	return next, nil
+}
+
+// rebuildIndex throws the index and time index away and builds them again from the records in the store.
+func (s *segment) rebuildIndex() (Repair, error) {
+	s.index.Truncate(0)
+	if err := s.timeIndex.Truncate(0); err != nil {
+		return Repair{BaseOffset: s.baseOffset}, err
+	}
+	r, err := s.recover()
 	if err != nil {
-		return nil, err
+		// readers go by the entries rebuilt so far, and scan the store past them
+		s.entries.Store(s.index.size / endWidth)
 	}
-	return record, nil
+	return r, err
 }
 
-// IsMaxed returns whether the segment has reached its max size, either by writing too much to the store or the index.
+// IsMaxed returns whether the segment should be rolled, either because its store has grown too large or
+// because its oldest record is older than the configured maximum age.
 func (s *segment) IsMaxed() bool {
-	return s.store.size > s.config.Segment.MaxStoreBytes ||
-		s.index.size > s.config.Segment.MaxIndexBytes
+	if s.store.size > s.config.Segment.MaxStoreBytes {
+		return true
+	}
+	first, ok := s.timeIndex.First()
+	return ok && s.config.Segment.MaxAge > 0 && time.Since(time.Unix(0, first.timestamp)) >= s.config.Segment.MaxAge
+}
+
+// Sync commits the store and both indexes to stable storage.
+func (s *segment) Sync() error {
+	if err := s.store.Sync(); err != nil {
+		return err
+	}
+	if err := s.index.Sync(); err != nil {
+		return err
+	}
+	return s.timeIndex.Sync()
 }
 
 func (s *segment) Close() error {
+	if err := s.timeIndex.Close(); err != nil {
+		return err
+	}
 	if err := s.index.Close(); err != nil {
 		return err
 	}
@@ -117,6 +408,9 @@ func (s *segment) Remove() error {
 	if err := s.Close(); err != nil {
 		return err
 	}
+	if err := os.Remove(s.timeIndex.Name()); err != nil {
+		return err
+	}
 	if err := os.Remove(s.index.Name()); err != nil {
 		return err
 	}
@@ -1,12 +1,16 @@
 package log
 
 import (
+	"fmt"
 	"io"
 	"os"
+	"path"
 	"testing"
+	"time"
 
 	"github.com/sant470/distlogs/api/v1"
 	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
 )
 
 func TestSegment(t *testing.T) {
@@ -14,13 +18,14 @@ func TestSegment(t *testing.T) {
 	defer os.RemoveAll(dir)
 	want := api.Record{Value: []byte("hello world")}
 	c := Config{}
-	c.Segment.MaxIndexBytes = 1024
-	c.Segment.MaxIndexBytes = endWidth * 3
+	c.Segment.MaxStoreBytes = 1024
+	c.Segment.IndexGrowBytes = endWidth * 3
 	s, err := newSegment(dir, 16, c)
 	require.NoError(t, err)
-	require.Equal(t, uint64(16), s.nextOffset, s.nextOffset)
+	require.Equal(t, uint64(16), s.nextOffset.Load())
 	require.False(t, s.IsMaxed())
-	for i := uint64(0); i < 3; i++ {
+	// the index grows past the three entries it started with
+	for i := uint64(0); i < 6; i++ {
 		off, err := s.Append(&want)
 		require.NoError(t, err)
 		require.Equal(t, 16+i, off)
@@ -29,13 +34,9 @@ func TestSegment(t *testing.T) {
 		require.NoError(t, err)
 		require.Equal(t, want.Value, got.Value)
 	}
+	require.False(t, s.IsMaxed())
 
-	_, err = s.Append(&want)
-	require.Equal(t, io.EOF, err)
-	// mmaxed Index
-	require.True(t, s.IsMaxed())
 	c.Segment.MaxStoreBytes = uint64(len(want.Value) * 3)
-	c.Segment.MaxIndexBytes = 1024
 	s, err = newSegment(dir, 16, c)
 	require.NoError(t, err)
 	// maxed store
@@ -43,7 +44,358 @@ func TestSegment(t *testing.T) {
 
 	err = s.Remove()
 	require.NoError(t, err)
+	c.Segment.MaxStoreBytes = 1024
+	c.Segment.MaxAge = time.Millisecond
 	s, err = newSegment(dir, 16, c)
 	require.NoError(t, err)
 	require.False(t, s.IsMaxed())
+	_, err = s.Append(&want)
+	require.NoError(t, err)
+	// aged
+	require.Eventually(t, s.IsMaxed, time.Second, time.Millisecond)
+}
+
+func TestSegmentCorruptRecord(t *testing.T) {
+	dir, _ := os.MkdirTemp("", "segment_corrupt_test")
+	defer os.RemoveAll(dir)
+	c := Config{}
+	c.Segment.IndexGrowBytes = 1024
+	s, err := newSegment(dir, 16, c)
+	require.NoError(t, err)
+	off, err := s.Append(&api.Record{Value: []byte("hello world")})
+	require.NoError(t, err)
+	// recovery can't tell a corrupt tail from a torn write, so corrupt a record that isn't the last
+	_, err = s.Append(&api.Record{Value: []byte("hello world")})
+	require.NoError(t, err)
+	_, pos, err := s.index.Read(1)
+	require.NoError(t, err)
+	require.NoError(t, s.Close())
+
+	f, err := os.OpenFile(s.store.Name(), os.O_RDWR, 0644)
+	require.NoError(t, err)
+	_, err = f.WriteAt([]byte{0xff}, int64(pos)-1)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	s, err = newSegment(dir, 16, c)
+	require.NoError(t, err)
+	_, err = s.Read(off)
+	require.Equal(t, api.ErrCorruptRecord{Offset: off, Pos: 0}, err)
+}
+
+func TestSegmentSparseIndex(t *testing.T) {
+	want := &api.Record{Value: []byte("hello world")}
+	// every record has the same width, its offset and timestamp encode to the same size
+	width := uint64(lenWidth + crcWidth + proto.Size(&api.Record{
+		Value: want.Value, Offset: 16, Timestamp: time.Now().UnixNano(),
+	}))
+
+	for scenario, tc := range map[string]struct {
+		config  func(c *Config)
+		indexed []uint32
+	}{
+		"every 4 records": {
+			config:  func(c *Config) { c.Segment.IndexIntervalRecords = 4 },
+			indexed: []uint32{0, 4, 8},
+		},
+		"every 3 records' bytes": {
+			config:  func(c *Config) { c.Segment.IndexIntervalBytes = 3 * width },
+			indexed: []uint32{0, 3, 6, 9},
+		},
+	} {
+		t.Run(scenario, func(t *testing.T) {
+			dir, _ := os.MkdirTemp("", "segment_sparse_test")
+			defer os.RemoveAll(dir)
+			c := Config{}
+			c.Segment.MaxStoreBytes = 1024
+			c.Segment.IndexGrowBytes = 1024
+			tc.config(&c)
+			s, err := newSegment(dir, 16, c)
+			require.NoError(t, err)
+			for i := 0; i < 10; i++ {
+				_, err = s.Append(want)
+				require.NoError(t, err)
+			}
+			// a batch is a single frame, indexed by its first record at most
+			_, err = s.AppendBatch([]*api.Record{want, want, want})
+			require.NoError(t, err)
+
+			check := func(s *segment) {
+				t.Helper()
+				var indexed []uint32
+				for i := uint64(0); i < s.entries.Load(); i++ {
+					off, _ := s.index.entry(i)
+					indexed = append(indexed, off)
+				}
+				require.Equal(t, tc.indexed, indexed)
+				for off := uint64(16); off < 29; off++ {
+					got, err := s.Read(off)
+					require.NoError(t, err)
+					require.Equal(t, want.Value, got.Value)
+					require.Equal(t, off, got.Offset)
+				}
+				_, err = s.Read(29)
+				require.Equal(t, io.EOF, err)
+			}
+			check(s)
+			require.NoError(t, s.Close())
+
+			// a clean shutdown leaves nothing to repair
+			s, err = newSegment(dir, 16, c)
+			require.NoError(t, err)
+			require.False(t, s.repair.Repaired())
+			check(s)
+
+			// the last entry didn't make it
+			s.index.Truncate(uint64(len(tc.indexed) - 1))
+			crash(t, s)
+			s, err = newSegment(dir, 16, c)
+			require.NoError(t, err)
+			require.Equal(t, uint64(1), s.repair.RebuiltIndexEntries)
+			check(s)
+			require.NoError(t, s.Close())
+		})
+	}
+}
+
+func TestSegmentUnknownCodec(t *testing.T) {
+	dir, _ := os.MkdirTemp("", "segment_codec_test")
+	defer os.RemoveAll(dir)
+	c := Config{}
+	c.Segment.IndexGrowBytes = 1024
+	s, err := newSegment(dir, 16, c)
+	require.NoError(t, err)
+	_, err = s.Append(&api.Record{Value: []byte("hello world")})
+	require.NoError(t, err)
+	// a batch written by a log with a codec this one doesn't have
+	p, err := proto.Marshal(&api.Record{Value: []byte("compressed"), Offset: 17})
+	require.NoError(t, err)
+	payload, err := encodeBatch(uncompressed, [][]byte{p})
+	require.NoError(t, err)
+	payload[0] = 250
+	_, positions, err := s.store.AppendFrames([]frame{{version: frameBatch, payload: payload}})
+	require.NoError(t, err)
+	_, _, err = s.readPayloads(positions[0])
+	require.Equal(t, api.ErrUnknownCodec{ID: 250}, err)
+	size := s.store.size
+	crash(t, s)
+
+	// recovery leaves the batch alone
+	_, err = newSegment(dir, 16, c)
+	require.Equal(t, api.ErrUnknownCodec{ID: 250}, err)
+	fi, err := os.Stat(path.Join(dir, "16.store"))
+	require.NoError(t, err)
+	require.Equal(t, int64(size), fi.Size())
+}
+
+func TestSegmentCompressedBatch(t *testing.T) {
+	dir, _ := os.MkdirTemp("", "segment_batch_test")
+	defer os.RemoveAll(dir)
+	c := Config{}
+	c.Segment.MaxStoreBytes = 1024
+	c.Segment.IndexGrowBytes = 1024
+	c.Compression.Codec = Gzip
+	s, err := newSegment(dir, 16, c)
+	require.NoError(t, err)
+	var records []*api.Record
+	for i := 0; i < 5; i++ {
+		records = append(records, &api.Record{Value: []byte(fmt.Sprintf(`{"greeting": "hello world", "n": %d}`, i))})
+	}
+	off, err := s.AppendBatch(records)
+	require.NoError(t, err)
+	require.Equal(t, uint64(16), off)
+	// a single frame, smaller than the records are
+	var size int
+	for _, record := range records {
+		size += proto.Size(record)
+	}
+	require.Less(t, s.store.size, uint64(size))
+	for i, want := range records {
+		_, pos := s.index.entry(uint64(i))
+		require.Equal(t, uint64(0), pos)
+		got, err := s.Read(16 + uint64(i))
+		require.NoError(t, err)
+		require.Equal(t, want.Value, got.Value)
+		require.Equal(t, 16+uint64(i), got.Offset)
+	}
+
+	// the index entries for the end of the batch didn't make it
+	s.index.Truncate(2)
+	crash(t, s)
+	s, err = newSegment(dir, 16, c)
+	require.NoError(t, err)
+	require.Equal(t, Repair{BaseOffset: 16, RebuiltIndexEntries: 3, TrimmedIndexBytes: 1024 - 5*endWidth}, s.repair)
+	for i, want := range records {
+		got, err := s.Read(16 + uint64(i))
+		require.NoError(t, err)
+		require.Equal(t, want.Value, got.Value)
+	}
+	require.NoError(t, s.Close())
+}
+
+// crash abandons a segment the way a dying process would: whatever reached the files stays there,
+// nothing is synced or truncated.
+func crash(t *testing.T, s *segment) {
+	t.Helper()
+	require.NoError(t, s.store.buf.Flush())
+	require.NoError(t, s.store.File.Close())
+	require.NoError(t, s.index.file.Close())
+}
+
+func TestSegmentRecoverCorruptFrame(t *testing.T) {
+	c := Config{}
+	c.Segment.IndexGrowBytes = 1024
+	for scenario, tc := range map[string]struct {
+		// corrupt is the record whose frame gets a flipped byte
+		corrupt uint64
+		torn    bool
+	}{
+		"last frame is a torn tail":               {corrupt: 2, torn: true},
+		"frame with intact frames after it fails": {corrupt: 1},
+	} {
+		t.Run(scenario, func(t *testing.T) {
+			dir, _ := os.MkdirTemp("", "segment_corrupt_test")
+			defer os.RemoveAll(dir)
+			s, err := newSegment(dir, 16, c)
+			require.NoError(t, err)
+			for i := 0; i < 3; i++ {
+				_, err = s.Append(&api.Record{Value: []byte("hello world")})
+				require.NoError(t, err)
+			}
+			_, pos := s.index.entry(tc.corrupt)
+			size := s.store.size
+			crash(t, s)
+			f, err := os.OpenFile(path.Join(dir, "16.store"), os.O_RDWR, 0644)
+			require.NoError(t, err)
+			_, err = f.WriteAt([]byte{0xff}, int64(pos)+int64(lenWidth+crcWidth))
+			require.NoError(t, err)
+			require.NoError(t, f.Close())
+			// the index is rebuilt from the store
+			require.NoError(t, os.Remove(path.Join(dir, "16.index")))
+
+			s, err = newSegment(dir, 16, c)
+			if !tc.torn {
+				require.Equal(t, api.ErrCorruptRecord{Offset: 16 + tc.corrupt, Pos: pos}, err)
+				fi, err := os.Stat(path.Join(dir, "16.store"))
+				require.NoError(t, err)
+				require.Equal(t, int64(size), fi.Size())
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, size-pos, s.repair.TruncatedStoreBytes)
+			require.Equal(t, 16+tc.corrupt, s.nextOffset.Load())
+			require.NoError(t, s.Close())
+		})
+	}
+}
+
+func TestSegmentRecordFields(t *testing.T) {
+	dir, _ := os.MkdirTemp("", "segment_fields_test")
+	defer os.RemoveAll(dir)
+	// a record written before headers and producer timestamps existed
+	old, err := proto.Marshal(&api.Record{Value: []byte("old"), Offset: 16})
+	require.NoError(t, err)
+	b := make([]byte, lenWidth+len(old))
+	enc.PutUint64(b, uint64(len(old)))
+	copy(b[lenWidth:], old)
+	require.NoError(t, os.WriteFile(path.Join(dir, "16.store"), b, 0644))
+
+	c := Config{}
+	c.Segment.IndexGrowBytes = 1024
+	s, err := newSegment(dir, 16, c)
+	require.NoError(t, err)
+	defer s.Close()
+	got, err := s.Read(16)
+	require.NoError(t, err)
+	require.Equal(t, []byte("old"), got.Value)
+	require.Nil(t, got.Headers)
+	require.Zero(t, got.ProducerTimestamp)
+
+	want := &api.Record{
+		Key:               []byte("key"),
+		Value:             []byte("new"),
+		Headers:           []*api.Header{{Key: []byte("trace"), Value: []byte("abc")}, {Key: []byte("empty")}},
+		ProducerTimestamp: 1234,
+	}
+	off, err := s.Append(want)
+	require.NoError(t, err)
+	require.Equal(t, uint64(17), off)
+	got, err = s.Read(off)
+	require.NoError(t, err)
+	require.Equal(t, want.Key, got.Key)
+	require.Equal(t, want.Value, got.Value)
+	require.Len(t, got.Headers, 2)
+	for i, h := range want.Headers {
+		require.Equal(t, h.Key, got.Headers[i].Key)
+		require.Equal(t, h.Value, got.Headers[i].Value)
+	}
+	require.Equal(t, int64(1234), got.ProducerTimestamp)
+	// the broker timestamp is the segment's own
+	require.NotZero(t, got.Timestamp)
+}
+
+func TestSegmentRecover(t *testing.T) {
+	want := &api.Record{Value: []byte("hello world")}
+	c := Config{}
+	c.Segment.IndexGrowBytes = 1024
+
+	for scenario, fn := range map[string]func(t *testing.T, s *segment) (Repair, uint64){
+		"preallocated index tail": func(t *testing.T, s *segment) (Repair, uint64) {
+			return Repair{TrimmedIndexBytes: 1024 - 3*endWidth}, 19
+		},
+		"torn store frame": func(t *testing.T, s *segment) (Repair, uint64) {
+			_, err := s.store.buf.Write([]byte{0, 0, 0})
+			require.NoError(t, err)
+			return Repair{TrimmedIndexBytes: 1024 - 3*endWidth, TruncatedStoreBytes: 3}, 19
+		},
+		"index entry without a store frame": func(t *testing.T, s *segment) (Repair, uint64) {
+			require.NoError(t, s.index.Write(3, s.store.size))
+			return Repair{DroppedIndexEntries: 1, TrimmedIndexBytes: 1024 - 3*endWidth}, 19
+		},
+		"store frame without an index entry": func(t *testing.T, s *segment) (Repair, uint64) {
+			p, err := proto.Marshal(&api.Record{Value: want.Value, Offset: 19})
+			require.NoError(t, err)
+			_, _, err = s.store.Append(p)
+			require.NoError(t, err)
+			return Repair{RebuiltIndexEntries: 1, TrimmedIndexBytes: 1024 - 4*endWidth}, 20
+		},
+		"missing index": func(t *testing.T, s *segment) (Repair, uint64) {
+			require.NoError(t, os.Remove(s.index.Name()))
+			return Repair{RebuiltIndexEntries: 3}, 19
+		},
+	} {
+		t.Run(scenario, func(t *testing.T) {
+			dir, _ := os.MkdirTemp("", "segment_recover_test")
+			defer os.RemoveAll(dir)
+			s, err := newSegment(dir, 16, c)
+			require.NoError(t, err)
+			for i := 0; i < 3; i++ {
+				_, err = s.Append(want)
+				require.NoError(t, err)
+			}
+			repair, next := fn(t, s)
+			repair.BaseOffset = 16
+			crash(t, s)
+
+			s, err = newSegment(dir, 16, c)
+			require.NoError(t, err)
+			require.Equal(t, repair, s.repair)
+			require.Equal(t, next, s.nextOffset.Load())
+			for off := uint64(16); off < next; off++ {
+				got, err := s.Read(off)
+				require.NoError(t, err)
+				require.Equal(t, want.Value, got.Value)
+			}
+			off, err := s.Append(want)
+			require.NoError(t, err)
+			require.Equal(t, next, off)
+			require.NoError(t, s.Close())
+
+			// a clean shutdown leaves nothing to repair
+			s, err = newSegment(dir, 16, c)
+			require.NoError(t, err)
+			require.False(t, s.repair.Repaired())
+			require.Equal(t, next+1, s.nextOffset.Load())
+		})
+	}
 }
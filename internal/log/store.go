@@ -11,20 +11,50 @@ package log
 import (
 	"bufio"
 	"encoding/binary"
+	"hash/crc32"
+	"io"
 	"os"
 	"sync"
+	"sync/atomic"
+
+	"github.com/sant470/distlogs/api/v1"
 )
 
 var (
 	enc      = binary.BigEndian
 	lenWidth = 8
+	crcWidth = 4
+	crcTable = crc32.MakeTable(crc32.Castagnoli)
+)
+
+// Every record in the store is framed by a uint64 header. The top byte of the header carries the
+// frame version and the remaining 56 bits carry the payload length. Stores written before checksums
+// existed only have the length, so their top byte is always zero.
+const (
+	frameLegacy  byte = 0 // length, payload
+	frameCRC     byte = 1 // length, CRC32C of the payload, payload
+	frameBatch   byte = 2 // like frameCRC, the payload holding a batch of records, see batch.go
+	frameLenMask      = 1<<56 - 1
 )
 
+// frame is a frame in the store, as it's appended or read back.
+type frame struct {
+	version byte
+	payload []byte
+	// width is the frame's full width on disk, set on the frames read back
+	width uint64
+}
+
 type store struct {
 	*os.File
 	mu   sync.Mutex
 	buf  *bufio.Writer
 	size uint64
+	// flushed is how much of the store the OS has, frames before it can be read without the lock
+	flushed atomic.Uint64
+	// synced is how much of the store's been fsynced since it was opened, and syncs how many fsyncs that took
+	synced atomic.Uint64
+	syncs  atomic.Uint64
 }
 
 func newStore(f *os.File) (*store, error) {
@@ -33,52 +63,182 @@ func newStore(f *os.File) (*store, error) {
 		return nil, err
 	}
 	size := uint64(fi.Size())
-	return &store{
+	s := &store{
 		File: f,
 		size: size,
 		buf:  bufio.NewWriter(f),
-	}, nil
+	}
+	s.flushed.Store(size)
+	return s, nil
 }
 
 // Append persist the given bytes to the store
-// We write the length of the record so that, when we read the record, we know how many bytes to read.
+// We write the length of the record so that, when we read the record, we know how many bytes to read,
+// followed by its checksum so that, when we read the record back, we know it hasn't changed on disk.
 func (s *store) Append(p []byte) (n uint64, pos uint64, err error) {
+	n, positions, err := s.AppendBatch([][]byte{p})
+	if err != nil {
+		return 0, 0, err
+	}
+	return n, positions[0], nil
+}
+
+// AppendBatch frames each of the given payloads like Append does and writes them all with a single write.
+func (s *store) AppendBatch(ps [][]byte) (n uint64, positions []uint64, err error) {
+	frames := make([]frame, len(ps))
+	for i, p := range ps {
+		frames[i] = frame{version: frameCRC, payload: p}
+	}
+	return s.AppendFrames(frames)
+}
+
+// AppendFrames writes the given frames, checksummed, with a single write.
+func (s *store) AppendFrames(frames []frame) (n uint64, positions []uint64, err error) {
+	var size int
+	for _, f := range frames {
+		size += lenWidth + crcWidth + len(f.payload)
+	}
+	b := make([]byte, 0, size)
+	positions = make([]uint64, len(frames))
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	pos = s.size
-	if err := binary.Write(s.buf, enc, uint64((len(p)))); err != nil {
-		return 0, 0, err
+	for i, f := range frames {
+		positions[i] = s.size + uint64(len(b))
+		b = enc.AppendUint64(b, uint64(f.version)<<56|uint64(len(f.payload)))
+		b = enc.AppendUint32(b, crc32.Checksum(f.payload, crcTable))
+		b = append(b, f.payload...)
 	}
-	w, err := s.buf.Write(p)
+	w, err := s.buf.Write(b)
 	if err != nil {
-		return 0, 0, err
+		return 0, nil, err
 	}
-	w += lenWidth
 	s.size += uint64(w)
-	return uint64(w), pos, nil
+	// large writes skip the buffer and go straight to the file
+	s.flushed.Store(s.size - uint64(s.buf.Buffered()))
+	return uint64(w), positions, nil
 }
 
+// Read returns the payload of the frame at pos, see ReadFrame.
 func (s *store) Read(pos uint64) ([]byte, error) {
+	f, err := s.ReadFrame(pos)
+	return f.payload, err
+}
+
+// ReadFrame returns the frame at pos, or api.ErrCorruptRecord when the frame can't be trusted: an unknown
+// version, a length running past the end of the store or a checksum mismatch.
+// Frames the OS already has are read without locking or flushing the store.
+func (s *store) ReadFrame(pos uint64) (frame, error) {
+	f, err := s.readFrame(pos, s.flushed.Load())
+	if err != io.EOF && err != io.ErrUnexpectedEOF {
+		return f, err
+	}
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	if err := s.flush(); err != nil {
+		return frame{}, err
+	}
+	f, err = s.readFrame(pos, s.size)
+	if err == io.ErrUnexpectedEOF {
+		return frame{}, api.ErrCorruptRecord{Pos: pos}
+	}
+	return f, err
+}
+
+// readFrame returns the frame at pos, reading no further than limit. It returns io.EOF if there's no frame
+// at pos and io.ErrUnexpectedEOF if the frame runs past limit. A frame failing its checksum still comes
+// with its width.
+func (s *store) readFrame(pos, limit uint64) (frame, error) {
+	if pos+uint64(lenWidth) > limit {
+		return frame{}, io.EOF
+	}
+	hdr := make([]byte, lenWidth)
+	if _, err := s.File.ReadAt(hdr, int64(pos)); err != nil {
+		return frame{}, err
+	}
+	version, size := byte(enc.Uint64(hdr)>>56), enc.Uint64(hdr)&frameLenMask
+	hdrWidth := uint64(lenWidth)
+	switch version {
+	case frameLegacy:
+	case frameCRC, frameBatch:
+		hdrWidth += uint64(crcWidth)
+	default:
+		return frame{}, api.ErrCorruptRecord{Pos: pos}
+	}
+	if pos+hdrWidth+size > limit {
+		return frame{}, io.ErrUnexpectedEOF
+	}
+	b := make([]byte, hdrWidth+size)
+	if _, err := s.File.ReadAt(b, int64(pos)); err != nil {
+		return frame{}, err
+	}
+	p := b[hdrWidth:]
+	if version != frameLegacy && crc32.Checksum(p, crcTable) != enc.Uint32(b[lenWidth:]) {
+		return frame{width: uint64(len(b))}, api.ErrCorruptRecord{Pos: pos}
+	}
+	return frame{version: version, payload: p, width: uint64(len(b))}, nil
+}
+
+// Flush hands everything buffered so far to the OS.
+func (s *store) Flush() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.flush()
+}
+
+func (s *store) flush() error {
 	if err := s.buf.Flush(); err != nil {
-		return nil, err
+		return err
 	}
-	size := make([]byte, lenWidth)
-	if _, err := s.File.ReadAt(size, int64(pos)); err != nil {
-		return nil, err
+	s.flushed.Store(s.size)
+	return nil
+}
+
+// Sync flushes the buffer and commits the store's file to stable storage. The fsync happens outside
+// the lock so appends can carry on while it runs.
+func (s *store) Sync() error {
+	s.mu.Lock()
+	err := s.flush()
+	size := s.size
+	s.mu.Unlock()
+	if err != nil {
+		return err
 	}
-	b := make([]byte, enc.Uint64(size))
-	if _, err := s.File.ReadAt(b, int64(pos+uint64(lenWidth))); err != nil {
-		return nil, err
+	if err = s.File.Sync(); err != nil {
+		return err
+	}
+	s.syncs.Add(1)
+	// syncs outside the log's lock may finish out of order
+	for synced := s.synced.Load(); synced < size && !s.synced.CompareAndSwap(synced, size); {
+		synced = s.synced.Load()
 	}
-	return b, nil
+	return nil
 }
 
-func (s *store) ReadAt(p []byte, off int64) (int, error) {
+// Truncate cuts the store back to size bytes, dropping everything written after it.
+func (s *store) Truncate(size uint64) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	if err := s.buf.Flush(); err != nil {
+		return err
+	}
+	if err := s.File.Truncate(int64(size)); err != nil {
+		return err
+	}
+	s.size = size
+	s.flushed.Store(size)
+	if s.synced.Load() > size {
+		s.synced.Store(size)
+	}
+	return nil
+}
+
+func (s *store) ReadAt(p []byte, off int64) (int, error) {
+	if uint64(off)+uint64(len(p)) <= s.flushed.Load() {
+		return s.File.ReadAt(p, off)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.flush(); err != nil {
 		return 0, err
 	}
 	return s.File.ReadAt(p, off)
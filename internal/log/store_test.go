@@ -4,12 +4,13 @@ import (
 	"os"
 	"testing"
 
+	"github.com/sant470/distlogs/api/v1"
 	"github.com/stretchr/testify/require"
 )
 
 var (
 	write = []byte("hello world")
-	width = uint64(len(write)) + uint64(lenWidth)
+	width = uint64(len(write)) + uint64(lenWidth+crcWidth)
 )
 
 func TestStoreAppendRead(t *testing.T) {
@@ -52,12 +53,13 @@ func testRead(t *testing.T, s *store) {
 func testReadAt(t *testing.T, s *store) {
 	t.Helper()
 	for i, off := uint64(1), int64(0); i < 4; i++ {
-		b := make([]byte, lenWidth)
+		b := make([]byte, lenWidth+crcWidth)
 		n, err := s.ReadAt(b, off)
 		require.NoError(t, err)
-		require.Equal(t, lenWidth, n)
+		require.Equal(t, lenWidth+crcWidth, n)
 		off += int64(n)
-		size := enc.Uint64(b)
+		require.Equal(t, frameCRC, b[0])
+		size := enc.Uint64(b) & frameLenMask
 		b = make([]byte, size)
 		n, err = s.ReadAt(b, off)
 		require.NoError(t, err)
@@ -67,6 +69,57 @@ func testReadAt(t *testing.T, s *store) {
 	}
 }
 
+func TestStoreCorruption(t *testing.T) {
+	f, err := os.CreateTemp("", "store_corruption_test")
+	require.NoError(t, err)
+	defer os.Remove(f.Name())
+	s, err := newStore(f)
+	require.NoError(t, err)
+	_, pos, err := s.Append(write)
+	require.NoError(t, err)
+	_, err = s.Read(pos)
+	require.NoError(t, err)
+
+	// flip a bit in the payload behind the store's back
+	b := make([]byte, 1)
+	_, err = s.ReadAt(b, int64(width)-1)
+	require.NoError(t, err)
+	b[0] ^= 0x01
+	g, err := os.OpenFile(f.Name(), os.O_RDWR, 0644)
+	require.NoError(t, err)
+	_, err = g.WriteAt(b, int64(width)-1)
+	require.NoError(t, err)
+	require.NoError(t, g.Close())
+
+	_, err = s.Read(pos)
+	require.Equal(t, api.ErrCorruptRecord{Pos: pos}, err)
+}
+
+func TestStoreReadLegacyFrame(t *testing.T) {
+	f, err := os.CreateTemp("", "store_legacy_test")
+	require.NoError(t, err)
+	defer os.Remove(f.Name())
+	// frames written before checksums existed only carry the length
+	b := make([]byte, lenWidth+len(write))
+	enc.PutUint64(b, uint64(len(write)))
+	copy(b[lenWidth:], write)
+	_, err = f.Write(b)
+	require.NoError(t, err)
+
+	s, err := newStore(f)
+	require.NoError(t, err)
+	read, err := s.Read(0)
+	require.NoError(t, err)
+	require.Equal(t, write, read)
+
+	_, pos, err := s.Append(write)
+	require.NoError(t, err)
+	require.Equal(t, uint64(len(b)), pos)
+	read, err = s.Read(pos)
+	require.NoError(t, err)
+	require.Equal(t, write, read)
+}
+
 func TestStoreClose(t *testing.T) {
 	f, err := os.CreateTemp("", "store_close_test")
 	require.NoError(t, err)
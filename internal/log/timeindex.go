@@ -0,0 +1,127 @@
+/*
+	The time index maps append times to offsets so the log can find where to start reading from a point in
+	time. It's sparse: a segment's first record always gets an entry, later records only once enough of the
+	store has been written since the last entry. To find the first record at or after a time, we start from
+	the last entry before that time and scan forward through the store.
+	Entries are few, so the index keeps them in memory and appends to its file unbuffered.
+*/
+
+package log
+
+import (
+	"io"
+	"os"
+	"sort"
+	"sync"
+)
+
+var (
+	tsWidth        uint64 = 8
+	timeEntryWidth        = tsWidth + offWidth
+)
+
+type timeEntry struct {
+	timestamp int64
+	off       uint32 // relative to the segment's base offset
+}
+
+type timeIndex struct {
+	file    *os.File
+	mu      sync.RWMutex
+	entries []timeEntry
+	// store bytes appended since the last entry
+	unindexed uint64
+}
+
+func newTimeIndex(f *os.File) (*timeIndex, error) {
+	b, err := io.ReadAll(f)
+	if err != nil {
+		return nil, err
+	}
+	t := &timeIndex{file: f}
+	for pos := uint64(0); pos+timeEntryWidth <= uint64(len(b)); pos += timeEntryWidth {
+		t.entries = append(t.entries, timeEntry{
+			timestamp: int64(enc.Uint64(b[pos : pos+tsWidth])),
+			off:       enc.Uint32(b[pos+tsWidth : pos+timeEntryWidth]),
+		})
+	}
+	return t, nil
+}
+
+// Observe accounts for a record of the given width appended at ts and indexes it if it's been long
+// enough since the last entry. Records without a timestamp, written before the log stamped them, are skipped.
+func (t *timeIndex) Observe(ts int64, off uint32, width, interval uint64) error {
+	if ts == 0 {
+		return nil
+	}
+	t.unindexed += width
+	if n := len(t.entries); n > 0 && (t.unindexed < interval || ts <= t.entries[n-1].timestamp) {
+		return nil
+	}
+	b := make([]byte, timeEntryWidth)
+	enc.PutUint64(b, uint64(ts))
+	enc.PutUint32(b[tsWidth:], off)
+	if _, err := t.file.Write(b); err != nil {
+		return err
+	}
+	t.mu.Lock()
+	t.entries = append(t.entries, timeEntry{timestamp: ts, off: off})
+	t.mu.Unlock()
+	t.unindexed = 0
+	return nil
+}
+
+// Lookup returns the relative offset to start scanning from for the first record appended at or after ts.
+func (t *timeIndex) Lookup(ts int64) uint32 {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	i := sort.Search(len(t.entries), func(i int) bool { return t.entries[i].timestamp >= ts })
+	if i == 0 {
+		return 0
+	}
+	return t.entries[i-1].off
+}
+
+// First returns the entry for the segment's first record, if any.
+func (t *timeIndex) First() (timeEntry, bool) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	if len(t.entries) == 0 {
+		return timeEntry{}, false
+	}
+	return t.entries[0], true
+}
+
+// Last returns the most recent entry, if any.
+func (t *timeIndex) Last() (timeEntry, bool) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	if len(t.entries) == 0 {
+		return timeEntry{}, false
+	}
+	return t.entries[len(t.entries)-1], true
+}
+
+// Truncate keeps the first n entries and drops the rest, along with any partly written entry.
+func (t *timeIndex) Truncate(n int) error {
+	if err := t.file.Truncate(int64(uint64(n) * timeEntryWidth)); err != nil {
+		return err
+	}
+	t.mu.Lock()
+	t.entries = t.entries[:n]
+	t.mu.Unlock()
+	t.unindexed = 0
+	return nil
+}
+
+func (t *timeIndex) Sync() error {
+	return t.file.Sync()
+}
+
+func (t *timeIndex) Close() error {
+	return t.file.Close()
+}
+
+func (t *timeIndex) Name() string {
+	return t.file.Name()
+}
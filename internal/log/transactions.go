@@ -0,0 +1,180 @@
+/*
+	A transaction's records are appended to each of its partitions as they're produced, flagged as
+	transactional, and a commit or abort marker from its producer ends it in every partition it touched,
+	see txnmanager.go. Each log keeps track of the transactions open in it, by the offset of their first
+	record, and of the offsets the aborted ones spanned.
+	The last stable offset is the first offset of the oldest transaction still open, or the log's next
+	offset if there's none: read committed consumers read up to it, skipping the markers and the records of
+	aborted transactions. A transaction is opened before its first record is visible to readers, and the
+	aborted ones are published before the last stable offset moves past them, so readers don't lock.
+	Readers look a record up among its producer's aborted transactions only. The aborted transactions are
+	forgotten once their records are gone, to retention or to compaction.
+	Like the producers' sequence numbers, transactions are rebuilt from the records when the log is set up,
+	and saved to the same snapshot.
+*/
+
+package log
+
+import (
+	"math"
+	"slices"
+	"sort"
+
+	"github.com/sant470/distlogs/api/v1"
+)
+
+// abortedTxn is the offsets an aborted transaction spanned, up to its abort marker.
+type abortedTxn struct {
+	ProducerID uint64
+	First      uint64
+	Last       uint64
+}
+
+// abortedIndex has the aborted transactions by producer, each producer's in the order they ended.
+type abortedIndex map[uint64][]abortedTxn
+
+// LastStableOffset returns the first offset read committed consumers can't read yet.
+func (l *Log) LastStableOffset() uint64 {
+	segments := l.loadSegments()
+	// read before firstOpen, which is published before the records it holds back
+	next := segments[len(segments)-1].nextOffset.Load()
+	return min(next, l.firstOpen.Load())
+}
+
+// ReadCommitted returns the first record at or after off read committed consumers can see. Once there's
+// none left below the last stable offset it returns api.ErrOffsetOutOfRange with the offset reading carries
+// on from.
+func (l *Log) ReadCommitted(off uint64) (*api.Record, error) {
+	lso := l.LastStableOffset()
+	aborted := l.loadAbortedTxns()
+	for off < lso {
+		record, err := l.Read(off)
+		if err != nil {
+			return nil, err
+		}
+		if record.Offset >= lso {
+			off = lso
+			break
+		}
+		if visible(record, aborted) {
+			return record, nil
+		}
+		off = record.Offset + 1
+	}
+	return nil, api.ErrOffsetOutOfRange{Offset: off}
+}
+
+// visible reports whether read committed consumers see the record, given the aborted transactions.
+func visible(record *api.Record, aborted abortedIndex) bool {
+	if record.Control != api.Control_DATA {
+		return false
+	}
+	return !inAbortedTxn(record, aborted)
+}
+
+func inAbortedTxn(record *api.Record, aborted abortedIndex) bool {
+	if !record.Transactional {
+		return false
+	}
+	// a producer's transactions don't overlap, only the first to end past the record can hold it
+	txns := aborted[record.ProducerId]
+	i := sort.Search(len(txns), func(i int) bool { return txns[i].Last > record.Offset })
+	return i < len(txns) && txns[i].First <= record.Offset
+}
+
+func (l *Log) loadAbortedTxns() abortedIndex {
+	if aborted := l.abortedTxns.Load(); aborted != nil {
+		return *aborted
+	}
+	return nil
+}
+
+// beginTxns opens the transactions the appends start before their records are visible, at the log's next
+// offset since their records don't have offsets yet. It's called with l.mu held, and returns the producers
+// whose transactions it opened.
+func (l *Log) beginTxns(pending []*appendRequest) []uint64 {
+	var opened []uint64
+	next := l.activeSegment.nextOffset.Load()
+	for _, req := range pending {
+		first := req.records[0]
+		if !first.Transactional || first.ProducerId == 0 || first.Control != api.Control_DATA {
+			continue
+		}
+		if _, ok := l.openTxns[first.ProducerId]; !ok {
+			l.openTxns[first.ProducerId] = next
+			opened = append(opened, first.ProducerId)
+		}
+	}
+	if len(opened) > 0 {
+		l.publishTxns()
+	}
+	return opened
+}
+
+// recordTxns goes over the appended records, opening the transactions beginTxns opened again at their
+// first record, or dropping them if none was appended, and ending the ones markers end. It's called with
+// l.mu held.
+func (l *Log) recordTxns(appended []*appendRequest, opened []uint64) {
+	for _, id := range opened {
+		delete(l.openTxns, id)
+	}
+	changed := len(opened) > 0
+	for _, req := range appended {
+		for _, record := range req.records {
+			if l.observeTxn(record) {
+				changed = true
+			}
+		}
+	}
+	if changed {
+		l.publishTxns()
+	}
+}
+
+// observeTxn applies the record to the log's transactions, and reports whether it changed them.
+func (l *Log) observeTxn(record *api.Record) bool {
+	id := record.ProducerId
+	if id == 0 {
+		return false
+	}
+	first, open := l.openTxns[id]
+	switch {
+	case record.Control != api.Control_DATA:
+		// a marker for a transaction that appended nothing to the log ends nothing
+		if !open {
+			return false
+		}
+		delete(l.openTxns, id)
+		if record.Control == api.Control_ABORT {
+			l.aborted = append(l.aborted, abortedTxn{ProducerID: id, First: first, Last: record.Offset})
+		}
+		return true
+	case record.Transactional && !open:
+		l.openTxns[id] = record.Offset
+		return true
+	}
+	return false
+}
+
+// publishTxns publishes the open transactions' first offset, and the aborted ones, to readers. It's
+// called with l.mu held.
+func (l *Log) publishTxns() {
+	aborted := make(abortedIndex)
+	for _, txn := range l.aborted {
+		aborted[txn.ProducerID] = append(aborted[txn.ProducerID], txn)
+	}
+	l.abortedTxns.Store(&aborted)
+	first := uint64(math.MaxUint64)
+	for _, off := range l.openTxns {
+		first = min(first, off)
+	}
+	l.firstOpen.Store(first)
+}
+
+// pruneAbortedTxns forgets the aborted transactions that ended before off, and reports whether there were
+// any. It's called with l.mu held.
+func (l *Log) pruneAbortedTxns(off uint64) bool {
+	n := len(l.aborted)
+	l.aborted = slices.DeleteFunc(l.aborted, func(txn abortedTxn) bool { return txn.Last < off })
+	return len(l.aborted) < n
+}
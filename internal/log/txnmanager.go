@@ -0,0 +1,282 @@
+/*
+	The manager coordinates the producers' transactions. It records each transaction in an internal topic,
+	keyed by producer and compacted down to the latest state of each, before anything depends on it: the
+	transaction is recorded when it begins, again whenever it adds a partition, before its first record goes
+	there, and once more when it's committed or aborted, before the markers are written. Once every partition
+	it added has its marker, a tombstone forgets it.
+	A transaction still in progress after the manager's TxnTimeout is aborted, so a producer that went away
+	doesn't hold back read committed consumers for good.
+	When the manager starts, the transactions left behind are finished: the ones being committed are
+	committed, the rest are aborted, their producers being gone.
+*/
+
+package log
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/sant470/distlogs/api/v1"
+	"go.uber.org/zap"
+)
+
+const (
+	transactionsTopic = internalTopicPrefix + "transactions"
+	// DefaultTxnTimeout is the TxnTimeout NewLogManager sets.
+	DefaultTxnTimeout = time.Minute
+)
+
+type txnStatus int
+
+const (
+	txnOngoing txnStatus = iota
+	txnCommitting
+	txnAborting
+)
+
+// transaction is a producer's transaction, as it's recorded in the transactions topic.
+type transaction struct {
+	Status     txnStatus
+	Partitions []txnPartition
+	// appends wait for the transaction's appends in flight before its markers are written
+	appends sync.WaitGroup
+	// timeout aborts the transaction if it's still in progress after the manager's TxnTimeout
+	timeout *time.Timer
+}
+
+type txnPartition struct {
+	Topic     string
+	Partition uint32
+}
+
+// loadTxns reads the transactions back into memory, creating the transactions topic if it doesn't exist,
+// and finishes them.
+func (m *LogManager) loadTxns() error {
+	if _, ok := m.topics[transactionsTopic]; !ok {
+		overrides := Config{}
+		overrides.Compaction.Enabled = true
+		overrides.Durability.Mode = SyncAlways
+		if err := m.createTopic(transactionsTopic, 1, overrides); err != nil {
+			return err
+		}
+	}
+	l, err := m.Partition(transactionsTopic, 0)
+	if err != nil {
+		return err
+	}
+	m.txns = make(map[uint64]*transaction)
+	it := l.Iterator(0)
+	for it.Next() {
+		record := it.Record()
+		if len(record.Key) != 8 {
+			continue
+		}
+		id := enc.Uint64(record.Key)
+		if len(record.Value) == 0 {
+			delete(m.txns, id)
+			continue
+		}
+		t := &transaction{}
+		if err = json.Unmarshal(record.Value, t); err != nil {
+			return err
+		}
+		m.txns[id] = t
+	}
+	if err = it.Err(); err != nil {
+		return err
+	}
+	for id, t := range m.txns {
+		control := api.Control_ABORT
+		if t.Status == txnCommitting {
+			control = api.Control_COMMIT
+		}
+		if err = m.endTxn(id, t, control); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// BeginTxn begins a transaction for the producer.
+func (m *LogManager) BeginTxn(producerID uint64) error {
+	if producerID == 0 {
+		return api.ErrInvalidProducer{ProducerID: producerID}
+	}
+	m.txnMu.Lock()
+	defer m.txnMu.Unlock()
+	if _, ok := m.txns[producerID]; ok {
+		return api.ErrTxnInProgress{ProducerID: producerID}
+	}
+	t := &transaction{}
+	if err := m.storeTxn(producerID, t); err != nil {
+		return err
+	}
+	m.txns[producerID] = t
+	if m.TxnTimeout > 0 {
+		t.timeout = time.AfterFunc(m.TxnTimeout, func() { m.expireTxn(producerID, t) })
+	}
+	return nil
+}
+
+// expireTxn aborts the producer's transaction t once it's timed out, unless it's already being ended.
+func (m *LogManager) expireTxn(producerID uint64, t *transaction) {
+	err := m.completeTxn(producerID, t, txnAborting, api.Control_ABORT)
+	if _, ok := err.(api.ErrNoTxn); ok || err == nil || err == ErrClosed {
+		return
+	}
+	// the transaction's left as it is, the producer can still abort it, or the manager once it starts again
+	zap.L().Named("log").Error(
+		"failed to abort timed out transaction",
+		zap.Uint64("producer_id", producerID),
+		zap.Error(err),
+	)
+}
+
+// AppendTxn appends the record as part of its producer's transaction, see AppendExpect.
+func (m *LogManager) AppendTxn(
+	topic string, partition *uint32, record *api.Record, expectedOffset *uint64, expectedKeyOffset *int64,
+) (uint32, uint64, error) {
+	if topic == "" {
+		topic = DefaultTopic
+	}
+	l, picked, err := m.pick(topic, partition, record)
+	if err != nil {
+		return 0, 0, err
+	}
+	m.txnMu.Lock()
+	t, ok := m.txns[record.ProducerId]
+	if !ok || t.Status != txnOngoing {
+		m.txnMu.Unlock()
+		return 0, 0, api.ErrNoTxn{ProducerID: record.ProducerId}
+	}
+	p := txnPartition{Topic: topic, Partition: picked}
+	if !slices.Contains(t.Partitions, p) {
+		t.Partitions = append(t.Partitions, p)
+		if err = m.storeTxn(record.ProducerId, t); err != nil {
+			t.Partitions = t.Partitions[:len(t.Partitions)-1]
+			m.txnMu.Unlock()
+			return 0, 0, err
+		}
+	}
+	t.appends.Add(1)
+	m.txnMu.Unlock()
+	defer t.appends.Done()
+	record.Transactional = true
+	off, err := l.AppendExpect(record, Expectation{NextOffset: expectedOffset, KeyOffset: expectedKeyOffset})
+	if err != nil {
+		return 0, 0, err
+	}
+	return picked, off, nil
+}
+
+// CommitTxn commits the producer's transaction, making its records visible to read committed consumers.
+func (m *LogManager) CommitTxn(producerID uint64) error {
+	return m.completeTxn(producerID, nil, txnCommitting, api.Control_COMMIT)
+}
+
+// AbortTxn aborts the producer's transaction, hiding its records from read committed consumers for good.
+func (m *LogManager) AbortTxn(producerID uint64) error {
+	return m.completeTxn(producerID, nil, txnAborting, api.Control_ABORT)
+}
+
+// completeTxn records that the transaction's being committed or aborted and ends it. A commit or abort
+// that failed halfway can be tried again. If expected isn't nil, the producer's transaction is only
+// completed if it's expected and still in progress.
+func (m *LogManager) completeTxn(
+	producerID uint64, expected *transaction, status txnStatus, control api.Control,
+) error {
+	m.txnMu.Lock()
+	t, ok := m.txns[producerID]
+	if !ok || (t.Status != txnOngoing && t.Status != status) ||
+		(expected != nil && (t != expected || t.Status != txnOngoing)) {
+		m.txnMu.Unlock()
+		return api.ErrNoTxn{ProducerID: producerID}
+	}
+	if t.Status != status {
+		if t.timeout != nil {
+			t.timeout.Stop()
+		}
+		t.Status = status
+		if err := m.storeTxn(producerID, t); err != nil {
+			t.Status = txnOngoing
+			m.txnMu.Unlock()
+			return err
+		}
+	}
+	m.txnMu.Unlock()
+	t.appends.Wait()
+	return m.endTxn(producerID, t, control)
+}
+
+// endTxn writes the markers ending the transaction to every partition it added, and forgets it.
+func (m *LogManager) endTxn(producerID uint64, t *transaction, control api.Control) error {
+	for _, p := range t.Partitions {
+		l, err := m.Partition(p.Topic, p.Partition)
+		// a topic deleted since has no records left to end
+		if _, ok := err.(api.ErrTopicNotFound); ok {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if _, err = l.Append(&api.Record{ProducerId: producerID, Control: control}); err != nil {
+			return err
+		}
+	}
+	m.txnMu.Lock()
+	defer m.txnMu.Unlock()
+	if err := m.storeTxn(producerID, nil); err != nil {
+		return err
+	}
+	delete(m.txns, producerID)
+	return nil
+}
+
+// storeTxn records the producer's transaction, or a tombstone for it if t is nil. It's called with
+// m.txnMu held, or while the manager's starting.
+func (m *LogManager) storeTxn(producerID uint64, t *transaction) error {
+	l, err := m.Partition(transactionsTopic, 0)
+	if err != nil {
+		return err
+	}
+	record := &api.Record{Key: enc.AppendUint64(nil, producerID)}
+	if t != nil {
+		if record.Value, err = json.Marshal(t); err != nil {
+			return err
+		}
+	}
+	_, err = l.Append(record)
+	return err
+}
+
+// LastStableOffset returns the topic's partition's last stable offset, see Log.LastStableOffset.
+func (m *LogManager) LastStableOffset(topic string, partition uint32) (uint64, error) {
+	l, err := m.Partition(topic, partition)
+	if err != nil {
+		return 0, err
+	}
+	return l.LastStableOffset(), nil
+}
+
+// ReadCommitted reads the first record at or after off read committed consumers can see from the topic's
+// partition, see Log.ReadCommitted.
+func (m *LogManager) ReadCommitted(topic string, partition uint32, off uint64) (*api.Record, error) {
+	l, err := m.Partition(topic, partition)
+	if err != nil {
+		return nil, err
+	}
+	return l.ReadCommitted(off)
+}
+
+// WaitForStableOffset waits for the topic's partition's last stable offset to move past off, see
+// Log.WaitForStableOffset.
+func (m *LogManager) WaitForStableOffset(ctx context.Context, topic string, partition uint32, off uint64) error {
+	l, err := m.Partition(topic, partition)
+	if err != nil {
+		return err
+	}
+	return l.WaitForStableOffset(ctx, off)
+}
@@ -0,0 +1,61 @@
+/*
+	Readers tailing the log wait for appends instead of polling for them. The log keeps a channel that the
+	committer closes once it's appended records, waking every waiter at once, and that the next waiter
+	replaces. Nothing is allocated while nobody's waiting, and a waiting reader costs no CPU.
+*/
+
+package log
+
+import "context"
+
+// WaitForOffset blocks until the record at off has been appended, the context is done or the log is closed.
+// It returns straight away if the log is already past off.
+func (l *Log) WaitForOffset(ctx context.Context, off uint64) error {
+	return l.waitFor(ctx, func() bool {
+		segments := l.loadSegments()
+		return segments[len(segments)-1].nextOffset.Load() > off
+	})
+}
+
+// WaitForStableOffset blocks until the last stable offset is past off, see WaitForOffset. It only moves
+// with appends, of the markers ending transactions.
+func (l *Log) WaitForStableOffset(ctx context.Context, off uint64) error {
+	return l.waitFor(ctx, func() bool { return l.LastStableOffset() > off })
+}
+
+func (l *Log) waitFor(ctx context.Context, done func() bool) error {
+	for {
+		// taken before checking, so an append in between still wakes us up
+		appended := l.appendedChan()
+		if done() {
+			return nil
+		}
+		select {
+		case <-appended:
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-l.stopCommit:
+			return ErrClosed
+		}
+	}
+}
+
+// appendedChan returns the channel closed at the next append.
+func (l *Log) appendedChan() <-chan struct{} {
+	l.waitMu.Lock()
+	defer l.waitMu.Unlock()
+	if l.appended == nil {
+		l.appended = make(chan struct{})
+	}
+	return l.appended
+}
+
+// notifyAppended wakes up the readers waiting for records.
+func (l *Log) notifyAppended() {
+	l.waitMu.Lock()
+	defer l.waitMu.Unlock()
+	if l.appended != nil {
+		close(l.appended)
+		l.appended = nil
+	}
+}
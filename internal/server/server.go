@@ -13,6 +13,8 @@ import (
 	"go.opencensus.io/trace"
 
 	"github.com/sant470/distlogs/api/v1"
+	"github.com/sant470/distlogs/internal/filter"
+	"github.com/sant470/distlogs/internal/group"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 	"google.golang.org/grpc"
@@ -26,13 +28,62 @@ const (
 	objectWildcard = "*"
 	produceAction  = "produce"
 	consumeAction  = "consume"
+	// defaultFetchMaxBytes bounds the fetches that don't, well under gRPC's default message size limit
+	defaultFetchMaxBytes = 1 << 20
 )
 
 type subjectContextKey struct{}
 
+var errNoCoordinator = status.Error(codes.Unimplemented, "the server has no consumer groups")
+
+// CommitLog addresses records by topic, partition and offset, an empty topic standing for the default one.
 type CommitLog interface {
-	Append(*api.Record) (uint64, error)
-	Read(uint64) (*api.Record, error)
+	// AppendExpect appends the record to the partition, or to the one the log picks if it's nil, if it goes
+	// to expectedOffset and the latest record with its key is at expectedKeyOffset, -1 for none, either left
+	// nil to expect nothing. It returns the partition and the record's offset in it.
+	AppendExpect(
+		topic string, partition *uint32, record *api.Record, expectedOffset *uint64, expectedKeyOffset *int64,
+	) (uint32, uint64, error)
+	Read(topic string, partition uint32, offset uint64) (*api.Record, error)
+	// Fetch reads the records from the offset on, up to maxRecords of them and maxBytes of their encoded
+	// size, no limit if left zero, and none if the offset is the partition's next one. It reads uncommitted,
+	// transaction markers and the records of open or aborted transactions included.
+	Fetch(topic string, partition uint32, offset uint64, maxRecords int, maxBytes uint64) ([]*api.Record, error)
+	// Watermarks returns the partition's lowest offset and its next offset to be appended.
+	Watermarks(topic string, partition uint32) (low, high uint64, err error)
+	// WaitForOffset blocks until the record at the offset is appended or the context is done.
+	WaitForOffset(ctx context.Context, topic string, partition uint32, offset uint64) error
+	// ReadCommitted reads the first record at or after the offset that read committed consumers can see,
+	// or fails with api.ErrOffsetOutOfRange with the offset to carry on from once there's none left below
+	// the partition's last stable offset.
+	ReadCommitted(topic string, partition uint32, offset uint64) (*api.Record, error)
+	LastStableOffset(topic string, partition uint32) (uint64, error)
+	// WaitForStableOffset blocks until the partition's last stable offset is past the offset or the context
+	// is done.
+	WaitForStableOffset(ctx context.Context, topic string, partition uint32, offset uint64) error
+	// CommitOffset commits the next offset the group reads from the partition.
+	CommitOffset(group, topic string, partition uint32, offset uint64) error
+	// CommittedOffset returns the offset the group last committed for the partition, or
+	// api.ErrNoCommittedOffset if it hasn't.
+	CommittedOffset(group, topic string, partition uint32) (uint64, error)
+	// InitProducer registers an idempotent producer and returns its ID.
+	InitProducer() (uint64, error)
+	BeginTxn(producerID uint64) error
+	// AppendTxn appends the record as part of its producer's transaction, see AppendExpect.
+	AppendTxn(
+		topic string, partition *uint32, record *api.Record, expectedOffset *uint64, expectedKeyOffset *int64,
+	) (uint32, uint64, error)
+	CommitTxn(producerID uint64) error
+	AbortTxn(producerID uint64) error
+}
+
+// GroupCoordinator keeps track of the consumer groups' members and the partitions they're assigned.
+type GroupCoordinator interface {
+	Join(groupID, memberID string, topics []string, strategy string) (group.Assignment, error)
+	Heartbeat(groupID, memberID string, generation uint64) error
+	Leave(groupID, memberID string) error
+	// Validate checks whether the member can commit offsets for the group's partition in the generation.
+	Validate(groupID, memberID string, generation uint64, topic string, partition uint32) error
 }
 
 type Authorizer interface {
@@ -40,8 +91,11 @@ type Authorizer interface {
 }
 
 type Config struct {
-	CommitLog  CommitLog
-	Authorizer Authorizer
+	CommitLog CommitLog
+	// Coordinator, if left nil, leaves the server without consumer groups: joining one fails with
+	// codes.Unimplemented, and offsets are committed without checking who commits them.
+	Coordinator GroupCoordinator
+	Authorizer  Authorizer
 }
 
 func NewGRPCServer(config *Config, opts ...grpc.ServerOption) (*grpc.Server, error) {
@@ -99,11 +153,78 @@ func (s *grpcServer) Produce(ctx context.Context, req *api.ProduceRequest) (*api
 	); err != nil {
 		return nil, err
 	}
-	offset, err := s.CommitLog.Append(req.Record)
+	// the fields the log trusts come from the request, checked by the log, never from the record: a forged
+	// marker or transactional flag would end or open other producers' transactions
+	record := req.Record
+	record.Control, record.Transactional = api.Control_DATA, false
+	record.ProducerId, record.Sequence = req.ProducerId, req.Sequence
+	appendRecord := s.CommitLog.AppendExpect
+	if req.Transactional {
+		appendRecord = s.CommitLog.AppendTxn
+	}
+	// a conflict fails with FailedPrecondition, see api.ErrConflict
+	partition, offset, err := appendRecord(req.Topic, req.Partition, record, req.ExpectedOffset, req.ExpectedKeyOffset)
+	if err != nil {
+		return nil, err
+	}
+	return &api.ProduceResponse{Offset: offset, Partition: partition}, nil
+}
+
+func (s *grpcServer) InitProducer(ctx context.Context, req *api.InitProducerRequest) (*api.InitProducerResponse, error) {
+	if err := s.Authorizer.Authorize(
+		subject(ctx),
+		objectWildcard,
+		produceAction,
+	); err != nil {
+		return nil, err
+	}
+	id, err := s.CommitLog.InitProducer()
 	if err != nil {
 		return nil, err
 	}
-	return &api.ProduceResponse{Offset: offset}, nil
+	return &api.InitProducerResponse{ProducerId: id}, nil
+}
+
+func (s *grpcServer) BeginTxn(ctx context.Context, req *api.BeginTxnRequest) (*api.BeginTxnResponse, error) {
+	if err := s.Authorizer.Authorize(
+		subject(ctx),
+		objectWildcard,
+		produceAction,
+	); err != nil {
+		return nil, err
+	}
+	if err := s.CommitLog.BeginTxn(req.ProducerId); err != nil {
+		return nil, err
+	}
+	return &api.BeginTxnResponse{}, nil
+}
+
+func (s *grpcServer) CommitTxn(ctx context.Context, req *api.CommitTxnRequest) (*api.CommitTxnResponse, error) {
+	if err := s.Authorizer.Authorize(
+		subject(ctx),
+		objectWildcard,
+		produceAction,
+	); err != nil {
+		return nil, err
+	}
+	if err := s.CommitLog.CommitTxn(req.ProducerId); err != nil {
+		return nil, err
+	}
+	return &api.CommitTxnResponse{}, nil
+}
+
+func (s *grpcServer) AbortTxn(ctx context.Context, req *api.AbortTxnRequest) (*api.AbortTxnResponse, error) {
+	if err := s.Authorizer.Authorize(
+		subject(ctx),
+		objectWildcard,
+		produceAction,
+	); err != nil {
+		return nil, err
+	}
+	if err := s.CommitLog.AbortTxn(req.ProducerId); err != nil {
+		return nil, err
+	}
+	return &api.AbortTxnResponse{}, nil
 }
 
 func (s *grpcServer) Consume(ctx context.Context, req *api.ConsumeRequest) (*api.ConsumeResponse, error) {
@@ -114,11 +235,122 @@ func (s *grpcServer) Consume(ctx context.Context, req *api.ConsumeRequest) (*api
 	); err != nil {
 		return nil, err
 	}
-	record, err := s.CommitLog.Read(req.Offset)
+	offset, err := s.consumeOffset(req)
+	if err != nil {
+		return nil, err
+	}
+	read := s.CommitLog.Read
+	if req.Isolation == api.Isolation_READ_COMMITTED {
+		read = s.CommitLog.ReadCommitted
+	}
+	record, err := read(req.Topic, req.Partition, offset)
+	if err != nil {
+		return nil, err
+	}
+	lso, err := s.CommitLog.LastStableOffset(req.Topic, req.Partition)
+	if err != nil {
+		return nil, err
+	}
+	return &api.ConsumeResponse{Record: record, LastStableOffset: lso}, nil
+}
+
+// Fetch reads a batch of records from the requested offset on, waiting up to MaxWaitMs for the first one
+// if there's none yet. Fetches are read uncommitted: the batch has every record appended, those of open or
+// aborted transactions and the transaction markers included, for read committed consumers use Consume.
+func (s *grpcServer) Fetch(ctx context.Context, req *api.FetchRequest) (*api.FetchResponse, error) {
+	if err := s.Authorizer.Authorize(
+		subject(ctx),
+		objectWildcard,
+		consumeAction,
+	); err != nil {
+		return nil, err
+	}
+	maxBytes := req.MaxBytes
+	if maxBytes == 0 {
+		maxBytes = defaultFetchMaxBytes
+	}
+	fetch := func() ([]*api.Record, error) {
+		return s.CommitLog.Fetch(req.Topic, req.Partition, req.Offset, int(req.MaxRecords), maxBytes)
+	}
+	records, err := fetch()
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 && req.MaxWaitMs > 0 {
+		wait, cancel := context.WithTimeout(ctx, time.Duration(req.MaxWaitMs)*time.Millisecond)
+		err = s.CommitLog.WaitForOffset(wait, req.Topic, req.Partition, req.Offset)
+		cancel()
+		switch {
+		case err == nil:
+			if records, err = fetch(); err != nil {
+				return nil, err
+			}
+		// nothing was appended in time, the batch is empty
+		case err == context.DeadlineExceeded && ctx.Err() == nil:
+		default:
+			return nil, err
+		}
+	}
+	low, high, err := s.CommitLog.Watermarks(req.Topic, req.Partition)
 	if err != nil {
 		return nil, err
 	}
-	return &api.ConsumeResponse{Record: record}, nil
+	return &api.FetchResponse{Records: records, HighWatermark: high, LowWatermark: low}, nil
+}
+
+// consumeOffset returns the offset a consume request reads from: the one it asks for, or else the group's
+// committed offset, or else the start of the partition.
+func (s *grpcServer) consumeOffset(req *api.ConsumeRequest) (uint64, error) {
+	if req.Offset != nil {
+		return *req.Offset, nil
+	}
+	if req.Group == "" {
+		return 0, nil
+	}
+	offset, err := s.CommitLog.CommittedOffset(req.Group, req.Topic, req.Partition)
+	if _, ok := err.(api.ErrNoCommittedOffset); ok {
+		return 0, nil
+	}
+	return offset, err
+}
+
+func (s *grpcServer) CommitOffset(ctx context.Context, req *api.CommitOffsetRequest) (*api.CommitOffsetResponse, error) {
+	if err := s.Authorizer.Authorize(
+		subject(ctx),
+		objectWildcard,
+		consumeAction,
+	); err != nil {
+		return nil, err
+	}
+	// a member rebalanced out of the partition mustn't move the offset of the member that has it now
+	if s.Coordinator != nil {
+		if err := s.Coordinator.Validate(
+			req.Group, req.MemberId, req.Generation, req.Topic, req.Partition,
+		); err != nil {
+			return nil, err
+		}
+	}
+	if err := s.CommitLog.CommitOffset(req.Group, req.Topic, req.Partition, req.Offset); err != nil {
+		return nil, err
+	}
+	return &api.CommitOffsetResponse{}, nil
+}
+
+func (s *grpcServer) FetchCommittedOffset(
+	ctx context.Context, req *api.FetchCommittedOffsetRequest,
+) (*api.FetchCommittedOffsetResponse, error) {
+	if err := s.Authorizer.Authorize(
+		subject(ctx),
+		objectWildcard,
+		consumeAction,
+	); err != nil {
+		return nil, err
+	}
+	offset, err := s.CommitLog.CommittedOffset(req.Group, req.Topic, req.Partition)
+	if err != nil {
+		return nil, err
+	}
+	return &api.FetchCommittedOffsetResponse{Offset: offset}, nil
 }
 
 // It implements bidirection streaming rpc, so the client can stream data into the server and server can tell the client whether each request succeeded.
@@ -138,25 +370,112 @@ func (s *grpcServer) ProduceStream(stream api.Log_ProduceStreamServer) error {
 	}
 }
 
+func (s *grpcServer) JoinGroup(ctx context.Context, req *api.JoinGroupRequest) (*api.JoinGroupResponse, error) {
+	if err := s.Authorizer.Authorize(
+		subject(ctx),
+		objectWildcard,
+		consumeAction,
+	); err != nil {
+		return nil, err
+	}
+	if s.Coordinator == nil {
+		return nil, errNoCoordinator
+	}
+	assignment, err := s.Coordinator.Join(req.Group, req.MemberId, req.Topics, req.Strategy)
+	if err != nil {
+		return nil, err
+	}
+	res := &api.JoinGroupResponse{MemberId: assignment.MemberID, Generation: assignment.Generation}
+	for _, p := range assignment.Partitions {
+		res.Partitions = append(res.Partitions, &api.TopicPartition{Topic: p.Topic, Partition: p.Partition})
+	}
+	return res, nil
+}
+
+func (s *grpcServer) Heartbeat(ctx context.Context, req *api.HeartbeatRequest) (*api.HeartbeatResponse, error) {
+	if err := s.Authorizer.Authorize(
+		subject(ctx),
+		objectWildcard,
+		consumeAction,
+	); err != nil {
+		return nil, err
+	}
+	if s.Coordinator == nil {
+		return nil, errNoCoordinator
+	}
+	if err := s.Coordinator.Heartbeat(req.Group, req.MemberId, req.Generation); err != nil {
+		return nil, err
+	}
+	return &api.HeartbeatResponse{}, nil
+}
+
+func (s *grpcServer) LeaveGroup(ctx context.Context, req *api.LeaveGroupRequest) (*api.LeaveGroupResponse, error) {
+	if err := s.Authorizer.Authorize(
+		subject(ctx),
+		objectWildcard,
+		consumeAction,
+	); err != nil {
+		return nil, err
+	}
+	if s.Coordinator == nil {
+		return nil, errNoCoordinator
+	}
+	if err := s.Coordinator.Leave(req.Group, req.MemberId); err != nil {
+		return nil, err
+	}
+	return &api.LeaveGroupResponse{}, nil
+}
+
 // It implements server side streaming, the client can tell the offset to read from and the server will keep streaming forever(even the records which are not the log yet!)
+// Without an offset, the stream resumes from the group's committed offset. Once it has caught up it waits for
+// the next append rather than polling the log.
 func (s *grpcServer) ConsumeStream(req *api.ConsumeRequest, stream api.Log_ConsumeStreamServer) error {
+	ctx := stream.Context()
+	offset, err := s.consumeOffset(req)
+	if err != nil {
+		return err
+	}
+	f, err := filter.Parse(req.Filter)
+	if err != nil {
+		return err
+	}
 	for {
-		select {
-		case <-stream.Context().Done():
-			return nil
-		default:
-			res, err := s.Consume(stream.Context(), req)
-			switch err.(type) {
-			case nil:
-			case api.ErrOffsetOutOfRange:
+		req.Offset = &offset
+		res, err := s.Consume(ctx, req)
+		switch err := err.(type) {
+		case nil:
+		case api.ErrOffsetOutOfRange:
+			low, _, werr := s.CommitLog.Watermarks(req.Topic, req.Partition)
+			if werr != nil {
+				return werr
+			}
+			// retention or truncation dropped the records below the lowest offset, reading carries on from it
+			if offset < low {
+				offset = low
 				continue
-			default:
-				return err
 			}
-			if err = stream.Send(res); err != nil {
+			wait := s.CommitLog.WaitForOffset
+			if req.Isolation == api.Isolation_READ_COMMITTED {
+				// what's left below the last stable offset is hidden, reading carries on from there
+				offset, wait = max(offset, err.Offset), s.CommitLog.WaitForStableOffset
+			}
+			if err := wait(ctx, req.Topic, req.Partition, offset); err != nil {
+				if ctx.Err() != nil {
+					return nil
+				}
 				return err
 			}
-			req.Offset++
+			continue
+		default:
+			return err
+		}
+		offset = res.Record.Offset + 1
+		// the records the filter skips are read past all the same
+		if !f.Match(res.Record) {
+			continue
+		}
+		if err = stream.Send(res); err != nil {
+			return err
 		}
 	}
 }
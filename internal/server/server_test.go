@@ -3,6 +3,7 @@ package server
 import (
 	"context"
 	"flag"
+	"fmt"
 	"io/ioutil"
 	"net"
 	"os"
@@ -12,6 +13,7 @@ import (
 	"github.com/sant470/distlogs/api/v1"
 	"github.com/sant470/distlogs/internal/auth"
 	"github.com/sant470/distlogs/internal/config"
+	"github.com/sant470/distlogs/internal/group"
 	"github.com/sant470/distlogs/internal/log"
 	"github.com/stretchr/testify/require"
 	"go.opencensus.io/examples/exporter"
@@ -20,6 +22,7 @@ import (
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
 )
 
 var debug = flag.Bool("debug", false, "Enable observability for debugging")
@@ -46,6 +49,18 @@ func TestServer(t *testing.T) {
 		"produce/consume a message to/from the log succeeds": testProduceConsume,
 		"produce/consume stream succeeds":                    testProduceConsumeStream,
 		"consume past log boundary fails":                    testConsumePastBoundary,
+		"consume stream after truncation succeeds":           testConsumeStreamTruncated,
+		"produce/consume by topic succeeds":                  testProduceConsumeTopics,
+		"produce/consume by partition succeeds":              testProduceConsumePartitions,
+		"consume from committed offset succeeds":             testConsumeCommittedOffset,
+		"group members share partitions":                     testGroupMembers,
+		"idempotent producer retries succeed":                testIdempotentProducer,
+		"read committed consumers see committed txns":        testTransactions,
+		"forged txn fields are ignored":                      testForgedTxnFields,
+		"produce expecting a stale offset fails":             testProduceExpect,
+		"record headers round trip":                          testRecordFields,
+		"consume stream filters records":                     testConsumeStreamFilter,
+		"fetch reads batches":                                testFetch,
 		"unauthorized failes":                                testUnauthorized,
 	} {
 		t.Run(scenario, func(t *testing.T) {
@@ -104,6 +119,18 @@ func setupTest(t *testing.T, fn func(*Config)) (api.LogClient, api.LogClient, *C
 	}
 }
 
+func TestServerWithoutCoordinator(t *testing.T) {
+	client, _, _, teardown := setupTest(t, func(c *Config) {
+		c.Coordinator = nil
+	})
+	defer teardown()
+	ctx := context.Background()
+	_, err := client.JoinGroup(ctx, &api.JoinGroupRequest{Group: "billing"})
+	require.Equal(t, codes.Unimplemented, status.Code(err))
+	_, err = client.CommitOffset(ctx, &api.CommitOffsetRequest{Group: "billing", Offset: 1})
+	require.NoError(t, err)
+}
+
 // startServer initializes the gRPC server with TLS
 func startServer(t *testing.T, l net.Listener, fn func(*Config)) (*grpc.Server, *Config, error) {
 	t.Helper()
@@ -123,13 +150,14 @@ func startServer(t *testing.T, l net.Listener, fn func(*Config)) (*grpc.Server,
 	dir, err := ioutil.TempDir("", "server-test")
 	require.NoError(t, err)
 
-	clog, err := log.NewLog(dir, log.Config{})
+	clog, err := log.NewLogManager(dir, log.Config{})
 	require.NoError(t, err)
 
 	authorizer := auth.NewAuthorizer(config.ACLModelFile, config.ACLPolicyFile)
 	cfg := &Config{
-		CommitLog:  clog,
-		Authorizer: authorizer,
+		CommitLog:   clog,
+		Coordinator: group.New(group.Config{Partitions: clog.Partitions}),
+		Authorizer:  authorizer,
 	}
 
 	server, err := NewGRPCServer(cfg, grpc.Creds(serverCreds))
@@ -174,17 +202,38 @@ func testProduceConsume(t *testing.T, client, _ api.LogClient, config *Config) {
 	want := &api.Record{Value: []byte("hello world")}
 	produce, err := client.Produce(ctx, &api.ProduceRequest{Record: want})
 	require.NoError(t, err)
-	consume, err := client.Consume(ctx, &api.ConsumeRequest{Offset: produce.Offset})
+	consume, err := client.Consume(ctx, &api.ConsumeRequest{Offset: proto.Uint64(produce.Offset)})
 	require.NoError(t, err)
 	require.Equal(t, want.Value, consume.Record.Value)
 	require.Equal(t, want.Offset, consume.Record.Offset)
 }
 
+func testRecordFields(t *testing.T, client, _ api.LogClient, config *Config) {
+	ctx := context.Background()
+	want := &api.Record{
+		Key:               []byte("user-1"),
+		Value:             []byte("hello world"),
+		Headers:           []*api.Header{{Key: []byte("trace-id"), Value: []byte("abc")}},
+		ProducerTimestamp: 1234,
+	}
+	produce, err := client.Produce(ctx, &api.ProduceRequest{Record: want})
+	require.NoError(t, err)
+	consume, err := client.Consume(ctx, &api.ConsumeRequest{Offset: proto.Uint64(produce.Offset)})
+	require.NoError(t, err)
+	require.Equal(t, want.Key, consume.Record.Key)
+	require.Equal(t, want.Value, consume.Record.Value)
+	require.Len(t, consume.Record.Headers, 1)
+	require.Equal(t, want.Headers[0].Key, consume.Record.Headers[0].Key)
+	require.Equal(t, want.Headers[0].Value, consume.Record.Headers[0].Value)
+	require.Equal(t, want.ProducerTimestamp, consume.Record.ProducerTimestamp)
+	require.NotZero(t, consume.Record.Timestamp)
+}
+
 func testConsumePastBoundary(t *testing.T, client, _ api.LogClient, config *Config) {
 	ctx := context.Background()
 	produce, err := client.Produce(ctx, &api.ProduceRequest{Record: &api.Record{Value: []byte("hello world!")}})
 	require.NoError(t, err)
-	consume, err := client.Consume(ctx, &api.ConsumeRequest{Offset: produce.Offset + 1})
+	consume, err := client.Consume(ctx, &api.ConsumeRequest{Offset: proto.Uint64(produce.Offset + 1)})
 	if consume != nil {
 		t.Fatal("consume not nil")
 	}
@@ -218,16 +267,361 @@ func testProduceConsumeStream(t *testing.T, client, _ api.LogClient, config *Con
 		}
 	}
 	{
-		stream, err := client.ConsumeStream(ctx, &api.ConsumeRequest{Offset: 0})
+		stream, err := client.ConsumeStream(ctx, &api.ConsumeRequest{Offset: proto.Uint64(0)})
 		require.NoError(t, err)
 		for i, record := range records {
 			res, err := stream.Recv()
 			require.NoError(t, err)
-			require.Equal(t, res.Record, &api.Record{Value: record.Value, Offset: uint64(i)})
+			require.Equal(t, record.Value, res.Record.Value)
+			require.Equal(t, uint64(i), res.Record.Offset)
 		}
 	}
 }
 
+func testFetch(t *testing.T, client, _ api.LogClient, config *Config) {
+	ctx := context.Background()
+	for i := 0; i < 5; i++ {
+		_, err := client.Produce(ctx, &api.ProduceRequest{Record: &api.Record{Value: []byte(fmt.Sprint(i))}})
+		require.NoError(t, err)
+	}
+	res, err := client.Fetch(ctx, &api.FetchRequest{Offset: 1, MaxRecords: 3})
+	require.NoError(t, err)
+	require.Len(t, res.Records, 3)
+	for i, record := range res.Records {
+		require.Equal(t, uint64(i+1), record.Offset)
+		require.Equal(t, []byte(fmt.Sprint(i+1)), record.Value)
+	}
+	require.Equal(t, uint64(0), res.LowWatermark)
+	require.Equal(t, uint64(5), res.HighWatermark)
+
+	// nothing's appended while the fetch waits
+	res, err = client.Fetch(ctx, &api.FetchRequest{Offset: 5, MaxWaitMs: 20})
+	require.NoError(t, err)
+	require.Empty(t, res.Records)
+
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		_, _ = client.Produce(ctx, &api.ProduceRequest{Record: &api.Record{Value: []byte("5")}})
+	}()
+	res, err = client.Fetch(ctx, &api.FetchRequest{Offset: 5, MaxWaitMs: 5000})
+	require.NoError(t, err)
+	require.Len(t, res.Records, 1)
+	require.Equal(t, uint64(5), res.Records[0].Offset)
+	require.Equal(t, uint64(6), res.HighWatermark)
+
+	_, err = client.Fetch(ctx, &api.FetchRequest{Offset: 7})
+	require.Equal(t, status.Code(api.ErrOffsetOutOfRange{}.GRPCStatus().Err()), status.Code(err))
+}
+
+func testConsumeStreamTruncated(t *testing.T, client, _ api.LogClient, config *Config) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	clog := config.CommitLog.(*log.LogManager)
+	overrides := log.Config{}
+	overrides.Segment.MaxStoreBytes = 150
+	require.NoError(t, clog.CreateTopic("truncated", 1, overrides))
+	for i := 0; i < 12; i++ {
+		_, err := client.Produce(ctx, &api.ProduceRequest{Record: &api.Record{Value: []byte("hello world")}, Topic: "truncated"})
+		require.NoError(t, err)
+	}
+	l, err := clog.Partition("truncated", 0)
+	require.NoError(t, err)
+	require.NoError(t, l.Truncate(6))
+	low, _ := l.Watermarks()
+	require.NotZero(t, low)
+
+	// streams from before the lowest offset carry on from it
+	for _, isolation := range []api.Isolation{api.Isolation_READ_UNCOMMITTED, api.Isolation_READ_COMMITTED} {
+		stream, err := client.ConsumeStream(ctx, &api.ConsumeRequest{
+			Offset:    proto.Uint64(0),
+			Topic:     "truncated",
+			Isolation: isolation,
+		})
+		require.NoError(t, err)
+		res, err := stream.Recv()
+		require.NoError(t, err)
+		require.Equal(t, low, res.Record.Offset)
+	}
+}
+
+func testConsumeStreamFilter(t *testing.T, client, _ api.LogClient, config *Config) {
+	ctx := context.Background()
+	for _, typ := range []string{"order", "refund", "refund", "order"} {
+		_, err := client.Produce(ctx, &api.ProduceRequest{Record: &api.Record{
+			Value:   []byte(typ),
+			Headers: []*api.Header{{Key: []byte("type"), Value: []byte(typ)}},
+		}})
+		require.NoError(t, err)
+	}
+	stream, err := client.ConsumeStream(ctx, &api.ConsumeRequest{
+		Offset: proto.Uint64(0),
+		Filter: `header.type == "order"`,
+	})
+	require.NoError(t, err)
+	for _, want := range []uint64{0, 3} {
+		res, err := stream.Recv()
+		require.NoError(t, err)
+		require.Equal(t, want, res.Record.Offset)
+		require.Equal(t, []byte("order"), res.Record.Value)
+	}
+
+	stream, err = client.ConsumeStream(ctx, &api.ConsumeRequest{Offset: proto.Uint64(0), Filter: `type == "order"`})
+	require.NoError(t, err)
+	_, err = stream.Recv()
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func testProduceConsumeTopics(t *testing.T, client, _ api.LogClient, config *Config) {
+	ctx := context.Background()
+	err := config.CommitLog.(*log.LogManager).CreateTopic("orders", 1, log.Config{})
+	require.NoError(t, err)
+	// each topic has offsets of its own
+	for i, req := range []*api.ProduceRequest{
+		{Record: &api.Record{Value: []byte("default")}},
+		{Record: &api.Record{Value: []byte("1st order")}, Topic: "orders"},
+		{Record: &api.Record{Value: []byte("2nd order")}, Topic: "orders"},
+	} {
+		produce, err := client.Produce(ctx, req)
+		require.NoError(t, err)
+		require.Equal(t, uint64(max(i-1, 0)), produce.Offset)
+	}
+	consume, err := client.Consume(ctx, &api.ConsumeRequest{Offset: proto.Uint64(1), Topic: "orders"})
+	require.NoError(t, err)
+	require.Equal(t, []byte("2nd order"), consume.Record.Value)
+
+	_, err = client.Consume(ctx, &api.ConsumeRequest{Offset: proto.Uint64(0), Topic: "payments"})
+	require.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func testProduceConsumePartitions(t *testing.T, client, _ api.LogClient, config *Config) {
+	ctx := context.Background()
+	err := config.CommitLog.(*log.LogManager).CreateTopic("clicks", 3, log.Config{})
+	require.NoError(t, err)
+	partition := uint32(2)
+	produce, err := client.Produce(ctx, &api.ProduceRequest{
+		Record:    &api.Record{Value: []byte("explicit")},
+		Topic:     "clicks",
+		Partition: &partition,
+	})
+	require.NoError(t, err)
+	require.Equal(t, uint32(2), produce.Partition)
+	require.Equal(t, uint64(0), produce.Offset)
+
+	// records with the same key land in the same partition
+	key := []byte("user-1")
+	produce, err = client.Produce(ctx, &api.ProduceRequest{Record: &api.Record{Value: []byte("1st"), Key: key}, Topic: "clicks"})
+	require.NoError(t, err)
+	again, err := client.Produce(ctx, &api.ProduceRequest{Record: &api.Record{Value: []byte("2nd"), Key: key}, Topic: "clicks"})
+	require.NoError(t, err)
+	require.Equal(t, produce.Partition, again.Partition)
+	consume, err := client.Consume(ctx, &api.ConsumeRequest{Offset: proto.Uint64(again.Offset), Topic: "clicks", Partition: again.Partition})
+	require.NoError(t, err)
+	require.Equal(t, []byte("2nd"), consume.Record.Value)
+
+	partition = 3
+	_, err = client.Produce(ctx, &api.ProduceRequest{Record: &api.Record{Value: []byte("nowhere")}, Topic: "clicks", Partition: &partition})
+	require.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func testConsumeCommittedOffset(t *testing.T, client, _ api.LogClient, config *Config) {
+	ctx := context.Background()
+	for _, value := range []string{"1st", "2nd", "3rd"} {
+		_, err := client.Produce(ctx, &api.ProduceRequest{Record: &api.Record{Value: []byte(value)}})
+		require.NoError(t, err)
+	}
+	_, err := client.FetchCommittedOffset(ctx, &api.FetchCommittedOffsetRequest{Group: "billing"})
+	require.Equal(t, codes.NotFound, status.Code(err))
+	// a group that hasn't committed starts from the beginning
+	consume, err := client.Consume(ctx, &api.ConsumeRequest{Group: "billing"})
+	require.NoError(t, err)
+	require.Equal(t, []byte("1st"), consume.Record.Value)
+
+	_, err = client.CommitOffset(ctx, &api.CommitOffsetRequest{Group: "billing", Offset: 1})
+	require.NoError(t, err)
+	fetch, err := client.FetchCommittedOffset(ctx, &api.FetchCommittedOffsetRequest{Group: "billing"})
+	require.NoError(t, err)
+	require.Equal(t, uint64(1), fetch.Offset)
+
+	stream, err := client.ConsumeStream(ctx, &api.ConsumeRequest{Group: "billing"})
+	require.NoError(t, err)
+	for _, want := range []string{"2nd", "3rd"} {
+		res, err := stream.Recv()
+		require.NoError(t, err)
+		require.Equal(t, []byte(want), res.Record.Value)
+	}
+}
+
+func testGroupMembers(t *testing.T, client, _ api.LogClient, config *Config) {
+	ctx := context.Background()
+	err := config.CommitLog.(*log.LogManager).CreateTopic("clicks", 4, log.Config{})
+	require.NoError(t, err)
+	first, err := client.JoinGroup(ctx, &api.JoinGroupRequest{Group: "billing", Topics: []string{"clicks"}})
+	require.NoError(t, err)
+	require.Len(t, first.Partitions, 4)
+	second, err := client.JoinGroup(ctx, &api.JoinGroupRequest{Group: "billing", Topics: []string{"clicks"}})
+	require.NoError(t, err)
+	// the first member still has the partitions assigned to the second
+	require.Empty(t, second.Partitions)
+	require.Greater(t, second.Generation, first.Generation)
+
+	// the first member finds out about the rebalance, and can't commit until it has joined again
+	_, err = client.Heartbeat(ctx, &api.HeartbeatRequest{Group: "billing", MemberId: first.MemberId, Generation: first.Generation})
+	require.Equal(t, codes.FailedPrecondition, status.Code(err))
+	commit := &api.CommitOffsetRequest{Group: "billing", Topic: "clicks", MemberId: first.MemberId, Generation: first.Generation}
+	_, err = client.CommitOffset(ctx, commit)
+	require.Equal(t, codes.FailedPrecondition, status.Code(err))
+	first, err = client.JoinGroup(ctx, &api.JoinGroupRequest{Group: "billing", MemberId: first.MemberId, Topics: []string{"clicks"}})
+	require.NoError(t, err)
+	require.Len(t, first.Partitions, 2)
+	// the first member gave them up, the second one gets them once it joins again
+	_, err = client.Heartbeat(ctx, &api.HeartbeatRequest{Group: "billing", MemberId: second.MemberId, Generation: second.Generation})
+	require.Equal(t, codes.FailedPrecondition, status.Code(err))
+	second, err = client.JoinGroup(ctx, &api.JoinGroupRequest{Group: "billing", MemberId: second.MemberId, Topics: []string{"clicks"}})
+	require.NoError(t, err)
+	require.Len(t, second.Partitions, 2)
+	require.NotEqual(t, first.Partitions, second.Partitions)
+	commit.Generation = first.Generation
+	_, err = client.CommitOffset(ctx, commit)
+	require.NoError(t, err)
+	// nor can it commit for a partition it wasn't handed
+	commit.Partition = second.Partitions[0].Partition
+	_, err = client.CommitOffset(ctx, commit)
+	require.Equal(t, codes.FailedPrecondition, status.Code(err))
+
+	_, err = client.LeaveGroup(ctx, &api.LeaveGroupRequest{Group: "billing", MemberId: second.MemberId})
+	require.NoError(t, err)
+	first, err = client.JoinGroup(ctx, &api.JoinGroupRequest{Group: "billing", MemberId: first.MemberId, Topics: []string{"clicks"}})
+	require.NoError(t, err)
+	require.Len(t, first.Partitions, 4)
+}
+
+func testIdempotentProducer(t *testing.T, client, _ api.LogClient, config *Config) {
+	ctx := context.Background()
+	producer, err := client.InitProducer(ctx, &api.InitProducerRequest{})
+	require.NoError(t, err)
+	produce := func(seq uint32, value string) (*api.ProduceResponse, error) {
+		return client.Produce(ctx, &api.ProduceRequest{
+			Record:     &api.Record{Value: []byte(value)},
+			ProducerId: producer.ProducerId,
+			Sequence:   seq,
+		})
+	}
+	first, err := produce(0, "1st")
+	require.NoError(t, err)
+	_, err = produce(1, "2nd")
+	require.NoError(t, err)
+	// the retry isn't appended again
+	retry, err := produce(0, "1st")
+	require.NoError(t, err)
+	require.Equal(t, first.Offset, retry.Offset)
+	_, err = client.Consume(ctx, &api.ConsumeRequest{Offset: proto.Uint64(2)})
+	require.Equal(t, status.Code(api.ErrOffsetOutOfRange{}.GRPCStatus().Err()), status.Code(err))
+
+	_, err = produce(3, "4th")
+	require.Equal(t, codes.FailedPrecondition, status.Code(err))
+}
+
+func testTransactions(t *testing.T, client, _ api.LogClient, config *Config) {
+	ctx := context.Background()
+	err := config.CommitLog.(*log.LogManager).CreateTopic("inventory", 1, log.Config{})
+	require.NoError(t, err)
+	producer, err := client.InitProducer(ctx, &api.InitProducerRequest{})
+	require.NoError(t, err)
+	stream, err := client.ConsumeStream(ctx, &api.ConsumeRequest{Topic: "inventory", Isolation: api.Isolation_READ_COMMITTED})
+	require.NoError(t, err)
+
+	// an order event and an inventory event go out together, or not at all
+	for i, end := range []func(context.Context, uint64) error{
+		func(ctx context.Context, id uint64) error {
+			_, err := client.AbortTxn(ctx, &api.AbortTxnRequest{ProducerId: id})
+			return err
+		},
+		func(ctx context.Context, id uint64) error {
+			_, err := client.CommitTxn(ctx, &api.CommitTxnRequest{ProducerId: id})
+			return err
+		},
+	} {
+		_, err = client.BeginTxn(ctx, &api.BeginTxnRequest{ProducerId: producer.ProducerId})
+		require.NoError(t, err)
+		for _, topic := range []string{"", "inventory"} {
+			_, err = client.Produce(ctx, &api.ProduceRequest{
+				Record:        &api.Record{Value: []byte(fmt.Sprintf("txn %d", i))},
+				Topic:         topic,
+				ProducerId:    producer.ProducerId,
+				Sequence:      uint32(i),
+				Transactional: true,
+			})
+			require.NoError(t, err)
+		}
+		uncommitted, err := client.Consume(ctx, &api.ConsumeRequest{Offset: proto.Uint64(uint64(2 * i))})
+		require.NoError(t, err)
+		require.Equal(t, uint64(2*i), uncommitted.LastStableOffset)
+		require.NoError(t, end(ctx, producer.ProducerId))
+	}
+	consume, err := client.Consume(ctx, &api.ConsumeRequest{Isolation: api.Isolation_READ_COMMITTED})
+	require.NoError(t, err)
+	require.Equal(t, []byte("txn 1"), consume.Record.Value)
+	require.Equal(t, uint64(4), consume.LastStableOffset)
+	res, err := stream.Recv()
+	require.NoError(t, err)
+	require.Equal(t, []byte("txn 1"), res.Record.Value)
+}
+
+func testForgedTxnFields(t *testing.T, client, _ api.LogClient, config *Config) {
+	ctx := context.Background()
+	producer, err := client.InitProducer(ctx, &api.InitProducerRequest{})
+	require.NoError(t, err)
+	_, err = client.BeginTxn(ctx, &api.BeginTxnRequest{ProducerId: producer.ProducerId})
+	require.NoError(t, err)
+	_, err = client.Produce(ctx, &api.ProduceRequest{
+		Record:        &api.Record{Value: []byte("in txn")},
+		ProducerId:    producer.ProducerId,
+		Transactional: true,
+	})
+	require.NoError(t, err)
+
+	// neither a marker for the producer's transaction nor a transactional flag get past a plain produce
+	for _, forged := range []*api.Record{
+		{Value: []byte("abort"), ProducerId: producer.ProducerId, Control: api.Control_ABORT},
+		{Value: []byte("open"), ProducerId: producer.ProducerId + 1, Transactional: true},
+	} {
+		produce, err := client.Produce(ctx, &api.ProduceRequest{Record: forged})
+		require.NoError(t, err)
+		consume, err := client.Consume(ctx, &api.ConsumeRequest{Offset: proto.Uint64(produce.Offset)})
+		require.NoError(t, err)
+		require.Equal(t, api.Control_DATA, consume.Record.Control)
+		require.False(t, consume.Record.Transactional)
+		require.Zero(t, consume.Record.ProducerId)
+		// the producer's transaction is still open
+		require.Equal(t, uint64(0), consume.LastStableOffset)
+	}
+
+	_, err = client.CommitTxn(ctx, &api.CommitTxnRequest{ProducerId: producer.ProducerId})
+	require.NoError(t, err)
+	consume, err := client.Consume(ctx, &api.ConsumeRequest{Offset: proto.Uint64(0), Isolation: api.Isolation_READ_COMMITTED})
+	require.NoError(t, err)
+	require.Equal(t, []byte("in txn"), consume.Record.Value)
+	// the forged transactional record holds nothing back
+	require.Equal(t, uint64(4), consume.LastStableOffset)
+}
+
+func testProduceExpect(t *testing.T, client, _ api.LogClient, config *Config) {
+	ctx := context.Background()
+	produce := func(key string, expectedOffset *uint64, expectedKeyOffset *int64) error {
+		_, err := client.Produce(ctx, &api.ProduceRequest{
+			Record:            &api.Record{Key: []byte(key), Value: []byte("event")},
+			ExpectedOffset:    expectedOffset,
+			ExpectedKeyOffset: expectedKeyOffset,
+		})
+		return err
+	}
+	require.NoError(t, produce("order-1", proto.Uint64(0), nil))
+	require.Equal(t, codes.FailedPrecondition, status.Code(produce("order-1", proto.Uint64(0), nil)))
+	require.NoError(t, produce("order-2", nil, proto.Int64(-1)))
+	require.NoError(t, produce("order-1", nil, proto.Int64(0)))
+	require.Equal(t, codes.FailedPrecondition, status.Code(produce("order-2", nil, proto.Int64(-1))))
+}
+
 func testUnauthorized(t *testing.T, _, client api.LogClient, config *Config) {
 	ctx := context.Background()
 	produce, err := client.Produce(ctx, &api.ProduceRequest{Record: &api.Record{Value: []byte("hello world!")}})
@@ -238,7 +632,7 @@ func testUnauthorized(t *testing.T, _, client api.LogClient, config *Config) {
 	if gotCode != wantCode {
 		t.Fatalf("got code %d, want %d", gotCode, wantCode)
 	}
-	consume, err := client.Consume(ctx, &api.ConsumeRequest{Offset: 0})
+	consume, err := client.Consume(ctx, &api.ConsumeRequest{Offset: proto.Uint64(0)})
 	if consume != nil {
 		t.Fatalf("consume response should be nil")
 	}